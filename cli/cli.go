@@ -1,12 +1,20 @@
 package cli
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/download"
+	"github.com/baumple/watchvault/mpv"
+	"github.com/baumple/watchvault/notify"
+	"github.com/baumple/watchvault/thumbnail"
+	"github.com/baumple/watchvault/utility"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
 )
 
 const (
@@ -26,70 +34,257 @@ const (
 )
 
 func makeTobBar(width int) string {
-	return makeLineBorders(strings.Repeat(HORIZONTAL_BAR, width-3), CORNER_TL, CORNER_TR, width)
+	return makeLineBorders(fillTitle("", width), CORNER_TL, CORNER_TR, width)
 }
 
 func makeTobBarTitle(title string, width int) string {
-	text := title +
-		strings.Repeat(HORIZONTAL_BAR, width-len(title)-3) // fill whitespace with horizontal bars
-	return makeLineBorders(text, CORNER_TL, CORNER_TR, width)
+	return makeLineBorders(fillTitle(title, width), CORNER_TL, CORNER_TR, width)
 }
 
 func makeSeparator(width int) string {
-	return makeLineBorders(strings.Repeat(HORIZONTAL_BAR, width-3), VERT_CROSS_RIGHT, VERT_CROSS_LEFT, width)
+	return makeLineBorders(fillTitle("", width), VERT_CROSS_RIGHT, VERT_CROSS_LEFT, width)
 }
 
 func makeSeparatorTitle(title string, width int) string {
-	text := title +
-		strings.Repeat(HORIZONTAL_BAR, width-len(title)-3) // fill whitespace with horizontal bars
-
 	return makeLine("", width) + // Create an empty line above so there is more padding
-		makeLineBorders(text, VERT_CROSS_RIGHT, VERT_CROSS_LEFT, width)
+		makeLineBorders(fillTitle(title, width), VERT_CROSS_RIGHT, VERT_CROSS_LEFT, width)
+}
+
+// fillTitle fills the space after title with horizontal bars
+func fillTitle(title string, width int) string {
+	title = utility.Truncate(title, width-3)
+	return title + strings.Repeat(HORIZONTAL_BAR, max(width-utility.Width(title)-3, 0))
 }
 
 func makeBottomBar(width int) string {
-	return makeLineBorders(strings.Repeat(HORIZONTAL_BAR, width-3), CORNER_BL, CORNER_BR, width)
+	return makeLineBorders(fillTitle("", width), CORNER_BL, CORNER_BR, width)
 }
 
 func makeLine(text string, width int) string {
-	res := VERTICAL_BAR
-	res += text
+	return makeLineBorders(text, VERTICAL_BAR, VERTICAL_BAR, width)
+}
+
+// makeLineBorders pads text to fit between the borders, text that is too
+// wide is cut off
+func makeLineBorders(text string, borderLeft string, borderRight string, width int) string {
+	return borderLeft + utility.Pad(text, width-3) + borderRight + "\n"
+}
+
+// pane is a view shown next to other views
+type pane struct {
+	view  string
+	width int
+}
 
-	for i := 0; i < width-len(text)-3; i++ {
-		res += " "
+// joinPanes puts the views of the panes next to each other. Every pane but
+// the last is cut or padded to its width, lines after height are cut off.
+// The last pane is not padded, the renderer counts the escape sequences
+// of its links as text.
+func joinPanes(height int, panes ...pane) string {
+	lines := make([][]string, len(panes))
+	rows := 0
+	for idx, pane := range panes {
+		lines[idx] = strings.Split(strings.TrimSuffix(pane.view, "\n"), "\n")
+		rows = max(rows, len(lines[idx]))
 	}
 
-	res += VERTICAL_BAR
-	return res + "\n"
+	text := ""
+	for row := 0; row < min(rows, height); row++ {
+		for idx, pane := range panes {
+			line := ""
+			if row < len(lines[idx]) {
+				line = lines[idx][row]
+			}
+			if idx == len(panes)-1 {
+				text += utility.Truncate(line, pane.width)
+			} else {
+				text += utility.Pad(line, pane.width)
+			}
+		}
+		text += "\n"
+	}
+	return text
 }
 
-func makeLineBorders(text string, borderLeft string, borderRight string, width int) string {
-	res := borderLeft
-	res += text
+// getDR returns the DataRetriever for the configured backend
+func getDR(config data.Config) (data.DataRetriever, error) {
+	switch config.Backend {
+	case data.BACKEND_JSON:
+		return data.NewJsonRetriever(config.VaultPath)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", config.Backend)
+	}
+}
+
+// parseConfig loads the config file and applies the environment
+// variables and the command line flags in args on top of it.
+// It returns the remaining arguments that are not flags.
+func parseConfig(args []string) (data.Config, []string, error) {
+	flags := flag.NewFlagSet("tubevault", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path of the config file")
+	apiKey := flags.String("api-key", "", "youtube api key")
+	backend := flags.String("backend", "", "storage backend (json)")
+	vault := flags.String("vault", "", "directory the vault is stored in")
+	opener := flags.String("opener", "", "command used to open playlists and videos")
+	refreshInterval := flags.String("refresh-interval", "", "interval between playlist refreshes, e.g. 30m or 1d")
+	newWindow := flags.String("new-window", "", "how long found videos are marked as new, e.g. 3d, 0 until viewed")
+	themeName := flags.String("theme", "", "color theme (default, mono, green)")
+	thumbnails := flags.String("thumbnails", "", "how thumbnails are shown (auto, kitty, sixel, halfblock, none)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return data.Config{}, nil, err
+	}
+
+	if *configPath == "" {
+		*configPath, err = data.GetConfigPath()
+		if err != nil {
+			return data.Config{}, nil, err
+		}
+	}
 
-	for i := 0; i < width-len(text)-3; i++ {
-		res += " "
+	config, err := data.LoadConfig(*configPath)
+	if err != nil {
+		return config, nil, err
 	}
 
-	res += borderRight
-	return res + "\n"
+	// only override the values of flags that were actually passed
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "api-key":
+			config.ApiKey = *apiKey
+		case "backend":
+			config.Backend = *backend
+		case "vault":
+			config.VaultPath = *vault
+		case "opener":
+			config.Opener = *opener
+		case "theme":
+			config.Theme = *themeName
+		case "thumbnails":
+			config.Thumbnails = *thumbnails
+		case "refresh-interval":
+			d, parseErr := data.ParseDuration(*refreshInterval)
+			if parseErr != nil {
+				err = fmt.Errorf("--refresh-interval: %w", parseErr)
+			}
+			config.RefreshInterval = data.Duration{Duration: d}
+		case "new-window":
+			d, parseErr := data.ParseDuration(*newWindow)
+			if parseErr != nil {
+				err = fmt.Errorf("--new-window: %w", parseErr)
+			}
+			config.NewWindow = data.Duration{Duration: d}
+		}
+	})
+
+	return config, flags.Args(), err
 }
 
-func getDR() data.DataRetriever {
-	return &data.JsonRetriever{}
+// fail prints err and exits the program
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "tubevault: %v\n", err)
+	os.Exit(1)
 }
 
-// StartCLI starts the command line interface
-func StartCLI() {
-	fmt.Print("\033[2J")
-	fmt.Print("\033[2;1H")
+// StartCLI starts the command line interface. Without a subcommand
+// the interactive interface is started.
+func StartCLI(args []string) {
+	config, args, err := parseConfig(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		fail(err)
+	}
+
+	if len(args) > 0 {
+		err = RunCommand(config, args, os.Stdout)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	startTUI(config)
+}
+
+// startTUI starts the interactive terminal interface
+func startTUI(config data.Config) {
+	err := config.RequireApiKey()
+	if err != nil {
+		fail(err)
+	}
+
+	keys, err := newKeymap(config.Keymap)
+	if err != nil {
+		fail(err)
+	}
+
+	t, err := getTheme(config.Theme)
+	if err != nil {
+		fail(err)
+	}
+
+	yt, err := data.NewYouTubeApi(config.ApiKey)
+	if err != nil {
+		fail(err)
+	}
+
+	notifier, err := notify.New(config.Notifications)
+	if err != nil {
+		fail(err)
+	}
+
+	err = thumbnail.CheckProtocol(config.Thumbnails)
+	if err != nil {
+		fail(err)
+	}
+	protocol := config.Thumbnails
+	if protocol == thumbnail.PROTOCOL_AUTO {
+		protocol = thumbnail.Detect(os.Getenv)
+	}
+
+	cacheDir, err := data.GetCacheDirPath()
+	if err != nil {
+		fail(err)
+	}
+
+	dr, err := getDR(config)
+	if err != nil {
+		fail(err)
+	}
+	defer dr.Close()
 
-	yt := data.NewYouTubeApi()
 	mainModel := initialModel()
-	mainModel.dr = getDR()
+	mainModel.dr = dr
 	mainModel.yt = yt
+	mainModel.notifier = notifier
+	mainModel.keys = keys
+	mainModel.theme = t
+	mainModel.opener = config.Opener
+	mainModel.refreshInterval = config.RefreshInterval.Duration
+	mainModel.newWindow = config.NewWindow.Duration
+	mainModel.player = mpv.New(config.Mpv, dr)
+	mainModel.downloader = download.New(config.Download, config.VaultPath, dr)
+	mainModel.thumbnails = thumbnail.NewCache(cacheDir)
+	mainModel.thumbnailProtocol = protocol
+
+	// the models draw the graphics to the same output as the renderer
+	output := termenv.NewOutput(os.Stdout, termenv.WithColorCache(true))
+	mainModel.output = output
 
-	p := tea.NewProgram(mainModel)
+	// the alternate screen puts the first line of the view in the first
+	// row, graphics are drawn at absolute positions
+	p := tea.NewProgram(mainModel, tea.WithAltScreen(), tea.WithOutput(output))
 	p.SetWindowTitle("watchvault")
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Could not start program: %v\n", err)
@@ -38,6 +38,32 @@ func TestWindowing(t *testing.T) {
 	}
 }
 
+type WindowSizeTest struct {
+	cursor int
+	width  int
+}
+
+var windowSizeTests = []WindowSizeTest{
+	{0, 1},
+	{0, 7},
+	{3, 7},
+	{0, 10},
+	{4, 10},
+	{12, 10},
+}
+
+func TestWindowSize(t *testing.T) {
+	for _, test := range windowSizeTests {
+		res := cli.GetWindow(test.cursor, test.width)
+		if res.End-res.Start < test.width-1 || res.End-res.Start > test.width {
+			t.Fatalf("Wanted about %d rows at cursor %d, got %d", test.width, test.cursor, res.End-res.Start)
+		}
+		if test.cursor < test.width/2 && res.End-res.Start != test.width {
+			t.Fatalf("Wanted a full first page of %d rows at cursor %d, got %d", test.width, test.cursor, res.End-res.Start)
+		}
+	}
+}
+
 type TestSplit struct {
 	inputS string
 	inputI int
@@ -48,6 +74,9 @@ type TestSplit struct {
 var splitTests = []TestSplit{
 	{"aabbcc", 2, []string{"aa", "bb", "cc"}},
 	{"aaabbbcc", 3, []string{"aaa", "bbb", "cc"}},
+	{"äöüß", 2, []string{"äö", "üß"}},
+	{"日本語", 3, []string{"日", "本", "語"}},
+	{"a日b", 1, []string{"a", "日", "b"}},
 }
 
 func TestSplitEveryN(t *testing.T) {
@@ -66,3 +95,128 @@ func TestSplitEveryN(t *testing.T) {
 	}
 }
 
+
+type WordWrapTest struct {
+	text  string
+	width int
+
+	expected []string
+}
+
+var wordWrapTests = []WordWrapTest{
+	{"aa bb cc", 5, []string{"aa bb", "cc"}},
+	{"aa  bb\n\ncc", 10, []string{"aa bb", "", "cc"}},
+	{"aaaaaaa b", 3, []string{"aaa", "aaa", "a b"}},
+	{"äöü äöü", 3, []string{"äöü", "äöü"}},
+	{"日本語 の本", 6, []string{"日本語", "の本"}},
+}
+
+func TestWordWrap(t *testing.T) {
+	for _, test := range wordWrapTests {
+		res := utility.WordWrap(test.text, test.width)
+
+		if len(res) != len(test.expected) {
+			t.Fatalf("Wanted %q, got %q", test.expected, res)
+		}
+
+		for i, out := range res {
+			if out != test.expected[i] {
+				t.Fatalf("Wanted %q, got %q", test.expected, res)
+			}
+		}
+	}
+}
+
+type FuzzyMatchTest struct {
+	pattern string
+	text    string
+
+	expected []int
+}
+
+var fuzzyMatchTests = []FuzzyMatchTest{
+	{"lec", "Lecture 12", []int{0, 1, 2}},
+	{"l12", "Lecture 12", []int{0, 8, 9}},
+	{"lin alg", "Linear Algebra", []int{0, 1, 2, 4, 8, 9}},
+	{"äb", "Ä b", []int{0, 2}},
+	{"xyz", "Lecture 12", nil},
+	{"12l", "Lecture 12", nil},
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	for _, test := range fuzzyMatchTests {
+		res := utility.FuzzyMatch(test.pattern, test.text)
+
+		if (res == nil) != (test.expected == nil) || len(res) != len(test.expected) {
+			t.Fatalf("Wanted %v for %q in %q, got %v", test.expected, test.pattern, test.text, res)
+		}
+
+		for i, out := range res {
+			if out != test.expected[i] {
+				t.Fatalf("Wanted %v for %q in %q, got %v", test.expected, test.pattern, test.text, res)
+			}
+		}
+	}
+}
+
+type WidthTest struct {
+	text string
+
+	expected int
+}
+
+var widthTests = []WidthTest{
+	{"abc", 3},
+	{"äöü", 3},
+	{"日本語", 6},
+	{"e\u0301", 1},
+	{"\033[1;31mred\033[0m", 3},
+	{"\033]8;;https://example.com\033\\link\033]8;;\033\\", 4},
+}
+
+func TestWidth(t *testing.T) {
+	for _, test := range widthTests {
+		res := utility.Width(test.text)
+		if res != test.expected {
+			t.Fatalf("Wanted width %d for %q, got %d", test.expected, test.text, res)
+		}
+	}
+}
+
+type TruncateTest struct {
+	text  string
+	width int
+
+	expected string
+}
+
+var truncateTests = []TruncateTest{
+	{"abc", 3, "abc"},
+	{"abcd", 3, "ab…"},
+	{"日本語", 5, "日本…"},
+	{"日本語", 4, "日…"},
+	{"\033[1mbold\033[0m text", 5, "\033[1mbold\033[0m…"},
+	{"\033[1mbold text\033[0m", 3, "\033[1mbo…\033[0m"},
+	{"abc", 0, ""},
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range truncateTests {
+		res := utility.Truncate(test.text, test.width)
+		if res != test.expected {
+			t.Fatalf("Wanted %q, got %q", test.expected, res)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	res := utility.Pad("日本", 6)
+	if res != "日本  " {
+		t.Fatalf("Wanted %q, got %q", "日本  ", res)
+	}
+
+	res = utility.Pad("\033[1mab\033[0m", 4)
+	if res != "\033[1mab\033[0m  " {
+		t.Fatalf("Wanted %q, got %q", "\033[1mab\033[0m  ", res)
+	}
+}
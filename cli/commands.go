@@ -0,0 +1,937 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/download"
+	"github.com/baumple/watchvault/feed"
+	"github.com/baumple/watchvault/mpv"
+	"github.com/baumple/watchvault/notify"
+	"github.com/baumple/watchvault/opener"
+)
+
+// command is a subcommand of the headless interface
+type command struct {
+	name        string
+	args        string
+	description string
+	// needsApi is set if the command talks to youtube
+	needsApi bool
+	run      func(ctx *commandContext, args []string) error
+}
+
+// commandContext holds everything a command needs to run
+type commandContext struct {
+	config data.Config
+	dr     data.DataRetriever
+	yt     *data.YouTubeApi
+	out    io.Writer
+
+	// notifier is notified about new videos found by a refresh
+	notifier *notify.Notifier
+
+	// json is set by the --json flag of every command
+	json bool
+}
+
+// refreshResult is the json output of the refresh command
+type refreshResult struct {
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	NewVideos int    `json:"new_videos"`
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"list", "", "list the tracked playlists", false, runList},
+		{"add", "<url|id>", "track a playlist", true, runAdd},
+		{"remove", "<playlist>", "stop tracking a playlist", false, runRemove},
+		{"videos", "<playlist>", "list the videos of a playlist", false, runVideos},
+		{"mark", "<video> --watched|--unwatched", "set the watched state of a video", false, runMark},
+		{"note", "<video> [text...|--clear]", "print or set the notes of a video", false, runNote},
+		{"refresh", "[playlist...]", "fetch new videos of the tracked playlists", true, runRefresh},
+		{"next", "[playlist]", "print the next unwatched video", false, runNext},
+		{"play", "<playlist> [video] [--queue]", "play a video in mpv and track the progress", false, runPlay},
+		{"download", "[playlist [video...]] [--list]", "download queued videos with yt-dlp", false, runDownload},
+		{"interval", "<playlist> [duration]", "print or set the refresh interval of a playlist", false, runInterval},
+		{"daemon", "[--once]", "refresh the playlists periodically in the background", true, runDaemon},
+		{"opener", "<playlist> [command|--reset]", "print or set the opener of a playlist", false, runOpener},
+		{"tag", "<playlist> [+tag|-tag...]", "print, add or remove tags of a playlist", false, runTag},
+		{"feed", "[--playlist id|--tag tag] [--out file]", "write an atom feed of new videos", false, runFeed},
+		{"digest", "[--since 7d] [--format md|html|text] [--mail]", "report new, watched and removed videos", false, runDigest},
+		{"serve", "[--addr host:port]", "serve the web interface and the json http api", false, runServe},
+		{"help", "", "print this help", false, runHelp},
+	}
+}
+
+// RunCommand runs the subcommand args[0] with the remaining arguments
+func RunCommand(config data.Config, args []string, out io.Writer) error {
+	var cmd *command
+	for idx := range commands {
+		if commands[idx].name == args[0] {
+			cmd = &commands[idx]
+		}
+	}
+	if cmd == nil {
+		return fmt.Errorf("unknown command %q, run \"tubevault help\" for a list of commands", args[0])
+	}
+
+	ctx := &commandContext{
+		config: config,
+		out:    out,
+	}
+
+	if cmd.needsApi {
+		err := config.RequireApiKey()
+		if err != nil {
+			return err
+		}
+		yt, err := data.NewYouTubeApi(config.ApiKey)
+		if err != nil {
+			return err
+		}
+		ctx.yt = &yt
+	}
+
+	notifier, err := notify.New(config.Notifications)
+	if err != nil {
+		return err
+	}
+	ctx.notifier = notifier
+
+	if cmd.name != "help" {
+		dr, err := getDR(config)
+		if err != nil {
+			return err
+		}
+		defer dr.Close()
+		ctx.dr = dr
+	}
+
+	return cmd.run(ctx, args[1:])
+}
+
+// flags returns a flag set for the command name that already contains
+// the --json flag
+func (ctx *commandContext) flags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("tubevault "+name, flag.ContinueOnError)
+	flags.BoolVar(&ctx.json, "json", false, "print the output as json")
+	return flags
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments and returns the positional arguments
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// print writes v as json if --json was passed, otherwise text is called
+// to print a human readable version
+func (ctx *commandContext) print(v any, text func(w io.Writer)) error {
+	if ctx.json {
+		encoder := json.NewEncoder(ctx.out)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		return encoder.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(ctx.out, 0, 4, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+// findPlaylist returns the tracked playlist with the given id or url
+func findPlaylist(playlists []data.Playlist, idOrUrl string) (*data.Playlist, error) {
+	id, err := data.ParsePlaylistId(idOrUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range playlists {
+		if playlists[idx].Id == id {
+			return &playlists[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("playlist %s is not tracked", id)
+}
+
+func printPlaylists(w io.Writer, summaries []data.PlaylistSummary) {
+	fmt.Fprintln(w, "ID\tWATCHED\tTITLE")
+	for _, summary := range summaries {
+		updated := ""
+		if summary.Updated {
+			updated = " *"
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%s%s\n",
+			summary.Id, summary.Watched, summary.Total, summary.Title, updated)
+	}
+}
+
+func printVideos(w io.Writer, summaries []data.VideoSummary) {
+	fmt.Fprintln(w, "WATCHED\tPUBLISHED\tID\tTITLE")
+	for _, summary := range summaries {
+		watched := "[ ]"
+		if summary.Watched {
+			watched = "[X]"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			watched, summary.PublishedAt.Format(time.DateOnly), summary.Id, summary.Title)
+	}
+}
+
+func runList(ctx *commandContext, args []string) error {
+	_, err := parseArgs(ctx.flags("list"), args)
+	if err != nil {
+		return err
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+
+	summaries := []data.PlaylistSummary{}
+	for idx := range playlists {
+		summaries = append(summaries, playlists[idx].Summary())
+	}
+
+	return ctx.print(summaries, func(w io.Writer) {
+		printPlaylists(w, summaries)
+	})
+}
+
+func runAdd(ctx *commandContext, args []string) error {
+	flags := ctx.flags("add")
+	interval := flags.String("interval", "", "refresh interval of the playlist, e.g. 6h or 1d")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("usage: tubevault add <url|id>")
+	}
+
+	id, err := data.ParsePlaylistId(args[0])
+	if err != nil {
+		return err
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	if _, err := findPlaylist(playlists, id); err == nil {
+		return fmt.Errorf("playlist %s is already tracked", id)
+	}
+
+	refreshInterval, err := data.ParseDuration(*interval)
+	if err != nil {
+		return err
+	}
+
+	playlist, err := ctx.yt.GetPlaylist(id)
+	if err != nil {
+		return err
+	}
+	playlist.RefreshInterval = data.Duration{Duration: refreshInterval}
+	playlist.LastRefreshed = time.Now()
+
+	err = ctx.dr.SavePlaylist(&playlist)
+	if err != nil {
+		return err
+	}
+
+	summary := playlist.Summary()
+	return ctx.print(summary, func(w io.Writer) {
+		fmt.Fprintf(w, "Added %s (%d videos)\n", playlist.Title, playlist.Length())
+	})
+}
+
+func runRemove(ctx *commandContext, args []string) error {
+	args, err := parseArgs(ctx.flags("remove"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("usage: tubevault remove <playlist>")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	playlist, err := findPlaylist(playlists, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = ctx.dr.DeletePlaylist(playlist.Id)
+	if err != nil {
+		return err
+	}
+
+	summary := playlist.Summary()
+	return ctx.print(summary, func(w io.Writer) {
+		fmt.Fprintf(w, "Removed %s\n", playlist.Title)
+	})
+}
+
+func runVideos(ctx *commandContext, args []string) error {
+	flags := ctx.flags("videos")
+	unwatched := flags.Bool("unwatched", false, "only list unwatched videos")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return errors.New("usage: tubevault videos <playlist>")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	playlist, err := findPlaylist(playlists, args[0])
+	if err != nil {
+		return err
+	}
+	playlist.Sort()
+
+	summaries := []data.VideoSummary{}
+	for idx := range playlist.Videos {
+		if *unwatched && playlist.Videos[idx].Watched {
+			continue
+		}
+		summaries = append(summaries, playlist.Videos[idx].Summary())
+	}
+
+	return ctx.print(summaries, func(w io.Writer) {
+		printVideos(w, summaries)
+	})
+}
+
+func runMark(ctx *commandContext, args []string) error {
+	flags := ctx.flags("mark")
+	watched := flags.Bool("watched", false, "mark the video as watched")
+	unwatched := flags.Bool("unwatched", false, "mark the video as unwatched")
+	playlistId := flags.String("playlist", "", "only look for the video in this playlist")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || *watched == *unwatched {
+		return errors.New("usage: tubevault mark <video> --watched|--unwatched")
+	}
+
+	video, err := ctx.findVideo(*playlistId, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = ctx.dr.UpdateVideoWatched(video.PlaylistId, video.Id, *watched)
+	if err != nil {
+		return err
+	}
+	video.SetWatched(*watched)
+
+	summary := video.Summary()
+	return ctx.print(summary, func(w io.Writer) {
+		state := "unwatched"
+		if video.Watched {
+			state = "watched"
+		}
+		fmt.Fprintf(w, "Marked %s as %s\n", video.Title, state)
+	})
+}
+
+func runNote(ctx *commandContext, args []string) error {
+	flags := ctx.flags("note")
+	clearNotes := flags.Bool("clear", false, "remove the notes")
+	playlistId := flags.String("playlist", "", "only look for the video in this playlist")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || (*clearNotes && len(args) > 1) {
+		return errors.New("usage: tubevault note <video> [text...|--clear]")
+	}
+
+	video, err := ctx.findVideo(*playlistId, args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 || *clearNotes {
+		video.Notes = strings.Join(args[1:], " ")
+		err = ctx.dr.UpdateVideoNotes(video.PlaylistId, video.Id, video.Notes)
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := video.Summary()
+	return ctx.print(summary, func(w io.Writer) {
+		if video.Notes != "" {
+			fmt.Fprintln(w, video.Notes)
+		}
+	})
+}
+
+// findVideo returns the tracked video with the given id, only looking in
+// the playlist with playlistId if it is not empty
+func (ctx *commandContext) findVideo(playlistId string, id string) (*data.Video, error) {
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return nil, err
+	}
+	if playlistId != "" {
+		playlist, err := findPlaylist(playlists, playlistId)
+		if err != nil {
+			return nil, err
+		}
+		playlists = []data.Playlist{*playlist}
+	}
+
+	for idx := range playlists {
+		video := playlists[idx].FindVideo(id)
+		if video != nil {
+			return video, nil
+		}
+	}
+	return nil, fmt.Errorf("video %s is not in a tracked playlist", id)
+}
+
+func runRefresh(ctx *commandContext, args []string) error {
+	args, err := parseArgs(ctx.flags("refresh"), args)
+	if err != nil {
+		return err
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		selected := []data.Playlist{}
+		for _, arg := range args {
+			playlist, err := findPlaylist(playlists, arg)
+			if err != nil {
+				return err
+			}
+			selected = append(selected, *playlist)
+		}
+		playlists = selected
+	}
+
+	results := []refreshResult{}
+	errs := []error{}
+	for idx := range playlists {
+		playlist, newVideos, err := data.RefreshPlaylist(ctx.dr, ctx.yt, playlists[idx].Id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not refresh %s: %w", playlists[idx].Title, err))
+			continue
+		}
+
+		err = ctx.notifier.Notify(playlist, newVideos)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tubevault: %v\n", err)
+		}
+
+		results = append(results, refreshResult{
+			Id:        playlist.Id,
+			Title:     playlist.Title,
+			NewVideos: len(newVideos),
+		})
+	}
+
+	// the playlists that were refreshed are printed even if others failed
+	err = ctx.print(results, func(w io.Writer) {
+		fmt.Fprintln(w, "NEW\tTITLE")
+		for _, result := range results {
+			fmt.Fprintf(w, "%d\t%s\n", result.NewVideos, result.Title)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+func runNext(ctx *commandContext, args []string) error {
+	args, err := parseArgs(ctx.flags("next"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 {
+		return errors.New("usage: tubevault next [playlist]")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		playlist, err := findPlaylist(playlists, args[0])
+		if err != nil {
+			return err
+		}
+
+		video := nextVideo(playlist)
+		if video == nil {
+			return fmt.Errorf("%s has no unwatched videos", playlist.Title)
+		}
+
+		summary := video.Summary()
+		return ctx.print(summary, func(w io.Writer) {
+			fmt.Fprintf(w, "%s\t%s\n", video.Title, video.URL())
+		})
+	}
+
+	summaries := []data.VideoSummary{}
+	for idx := range playlists {
+		video := nextVideo(&playlists[idx])
+		if video != nil {
+			summaries = append(summaries, video.Summary())
+		}
+	}
+
+	return ctx.print(summaries, func(w io.Writer) {
+		for _, summary := range summaries {
+			fmt.Fprintf(w, "%s\t%s\n", summary.Title, summary.Url)
+		}
+	})
+}
+
+func runPlay(ctx *commandContext, args []string) error {
+	flags := ctx.flags("play")
+	queue := flags.Bool("queue", false, "play the following unwatched videos afterwards")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: tubevault play <playlist> [video] [--queue]")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	playlist, err := findPlaylist(playlists, args[0])
+	if err != nil {
+		return err
+	}
+
+	// without a video the next unwatched one is played
+	video := nextVideo(playlist)
+	if len(args) == 2 {
+		video = playlist.FindVideo(args[1])
+		if video == nil {
+			return fmt.Errorf("video %s is %w", args[1], data.ErrNotTracked)
+		}
+	}
+	if video == nil {
+		return fmt.Errorf("%s has no unwatched videos", playlist.Title)
+	}
+
+	index := 0
+	for idx := range playlist.Videos {
+		if playlist.Videos[idx].Id == video.Id {
+			index = idx
+		}
+	}
+
+	videos := playlist.Videos[index : index+1]
+	if *queue {
+		videos = mpv.Queue(playlist.Videos, index)
+	}
+
+	played, err := mpv.New(ctx.config.Mpv, ctx.dr).Play(videos)
+	if err != nil {
+		return err
+	}
+
+	summaries := []data.VideoSummary{}
+	for idx := range played {
+		summaries = append(summaries, played[idx].Summary())
+	}
+
+	return ctx.print(summaries, func(w io.Writer) {
+		for idx := range played {
+			state := "unwatched"
+			if played[idx].Watched {
+				state = "watched"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", played[idx].Title, state, played[idx].Position)
+		}
+	})
+}
+
+// downloadResult is the json output of the download command
+type downloadResult struct {
+	Id         string  `json:"id"`
+	PlaylistId string  `json:"playlist_id"`
+	Title      string  `json:"title"`
+	Status     string  `json:"status"`
+	Progress   float64 `json:"progress"`
+	File       string  `json:"file,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+func runDownload(ctx *commandContext, args []string) error {
+	flags := ctx.flags("download")
+	list := flags.Bool("list", false, "only print the download state of the videos")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if !*list && len(args) > 0 {
+		playlists, err := ctx.dr.GetPlaylists()
+		if err != nil {
+			return err
+		}
+		playlist, err := findPlaylist(playlists, args[0])
+		if err != nil {
+			return err
+		}
+
+		_, err = download.Queue(ctx.dr, playlist.Id, args[1:]...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !*list {
+		signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err = download.New(ctx.config.Download, ctx.config.VaultPath, ctx.dr).Run(signalCtx)
+		if err != nil {
+			return err
+		}
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+
+	results := []downloadResult{}
+	for idx := range playlists {
+		for _, video := range playlists[idx].Videos {
+			if video.Download.Status == data.DOWNLOAD_NONE {
+				continue
+			}
+			results = append(results, downloadResult{
+				Id:         video.Id,
+				PlaylistId: video.PlaylistId,
+				Title:      video.Title,
+				Status:     video.Download.Status,
+				Progress:   video.Download.Progress,
+				File:       video.Download.File,
+				Error:      video.Download.Error,
+			})
+		}
+	}
+
+	return ctx.print(results, func(w io.Writer) {
+		for _, result := range results {
+			detail := result.File
+			if result.Error != "" {
+				detail = result.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.0f%%\t%s\n", result.Title, result.Status, result.Progress, detail)
+		}
+	})
+}
+
+func runInterval(ctx *commandContext, args []string) error {
+	args, err := parseArgs(ctx.flags("interval"), args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: tubevault interval <playlist> [duration]")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	playlist, err := findPlaylist(playlists, args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		interval, err := data.ParseDuration(args[1])
+		if err != nil {
+			return err
+		}
+		playlist.RefreshInterval = data.Duration{Duration: interval}
+		err = ctx.dr.SavePlaylist(playlist)
+		if err != nil {
+			return err
+		}
+	}
+
+	result := struct {
+		Id            string    `json:"id"`
+		Interval      string    `json:"interval"`
+		Default       bool      `json:"default"`
+		LastRefreshed time.Time `json:"last_refreshed"`
+		NextRefresh   time.Time `json:"next_refresh"`
+	}{
+		Id:            playlist.Id,
+		Interval:      playlist.Interval(ctx.config.RefreshInterval.Duration).String(),
+		Default:       playlist.RefreshInterval.Duration == 0,
+		LastRefreshed: playlist.LastRefreshed,
+		NextRefresh:   playlist.NextRefresh(ctx.config.RefreshInterval.Duration),
+	}
+
+	return ctx.print(result, func(w io.Writer) {
+		source := ""
+		if result.Default {
+			source = " (default)"
+		}
+		fmt.Fprintf(w, "%s refreshes every %s%s\n", playlist.Title, result.Interval, source)
+	})
+}
+
+func runOpener(ctx *commandContext, args []string) error {
+	flags := ctx.flags("opener")
+	reset := flags.Bool("reset", false, "use the configured opener again")
+	args, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 || (*reset && len(args) == 2) {
+		return errors.New("usage: tubevault opener <playlist> [command|--reset]")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	playlist, err := findPlaylist(playlists, args[0])
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 2 || *reset {
+		playlist.Opener = ""
+		if len(args) == 2 {
+			_, err = opener.Command(args[1], opener.ForPlaylist(playlist))
+			if err != nil {
+				return err
+			}
+			playlist.Opener = args[1]
+		}
+
+		err = ctx.dr.SavePlaylist(playlist)
+		if err != nil {
+			return err
+		}
+	}
+
+	result := struct {
+		Id      string `json:"id"`
+		Opener  string `json:"opener"`
+		Default bool   `json:"default"`
+	}{
+		Id:      playlist.Id,
+		Opener:  opener.Resolve(ctx.config.Opener, playlist),
+		Default: playlist.Opener == "",
+	}
+
+	return ctx.print(result, func(w io.Writer) {
+		source := ""
+		if result.Default {
+			source = " (default)"
+		}
+		fmt.Fprintf(w, "%s is opened with %s%s\n", playlist.Title, result.Opener, source)
+	})
+}
+
+func runTag(ctx *commandContext, args []string) error {
+	flags := ctx.flags("tag")
+
+	// "-tag" would be parsed as an unknown flag, so the tags are taken out
+	// before the flags are parsed
+	positional := []string{}
+	flagArgs := []string{}
+	for _, arg := range args {
+		switch {
+		case isTagChange(flags, arg), !strings.HasPrefix(arg, "-"):
+			positional = append(positional, arg)
+		default:
+			flagArgs = append(flagArgs, arg)
+		}
+	}
+
+	_, err := parseArgs(flags, flagArgs)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 {
+		return errors.New("usage: tubevault tag <playlist> [+tag|-tag...]")
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+	playlist, err := findPlaylist(playlists, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if len(positional) > 1 {
+		for _, arg := range positional[1:] {
+			switch {
+			case strings.HasPrefix(arg, "-"):
+				playlist.RemoveTag(arg[1:])
+			default:
+				playlist.AddTag(strings.TrimPrefix(arg, "+"))
+			}
+		}
+
+		err = ctx.dr.SavePlaylist(playlist)
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := playlist.Summary()
+	return ctx.print(summary, func(w io.Writer) {
+		fmt.Fprintln(w, strings.Join(playlist.Tags, " "))
+	})
+}
+
+// isTagChange reports whether arg adds (+tag) or removes (-tag) a tag
+// rather than being a flag of flags
+func isTagChange(flags *flag.FlagSet, arg string) bool {
+	if strings.HasPrefix(arg, "+") {
+		return len(arg) > 1
+	}
+	if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") || len(arg) == 1 {
+		return false
+	}
+	name, _, _ := strings.Cut(arg[1:], "=")
+	return flags.Lookup(name) == nil
+}
+
+func runFeed(ctx *commandContext, args []string) error {
+	flags := ctx.flags("feed")
+	playlistId := flags.String("playlist", "", "only include this playlist")
+	tag := flags.String("tag", "", "only include playlists with this tag")
+	out := flags.String("out", "", "write the feed to this file instead of stdout")
+	limit := flags.Int("limit", feed.DEFAULT_LIMIT, "maximum number of entries")
+	_, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	playlists, err := ctx.dr.GetPlaylists()
+	if err != nil {
+		return err
+	}
+
+	options := feed.Options{Limit: *limit}
+	var atom *feed.Feed
+	switch {
+	case *playlistId != "":
+		playlist, err := findPlaylist(playlists, *playlistId)
+		if err != nil {
+			return err
+		}
+		atom = feed.ForPlaylist(playlist, options)
+	case *tag != "":
+		atom = feed.ForTag(playlists, *tag, options)
+	default:
+		atom = feed.ForVault(playlists, options)
+	}
+
+	if *out == "" {
+		return atom.Write(ctx.out)
+	}
+
+	// write the file atomically so feed readers never see half a feed
+	file, err := os.CreateTemp(filepath.Dir(*out), ".feed-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = atom.Write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// feed files are usually served by a web server, so they are readable
+	err = os.Chmod(file.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), *out)
+}
+
+// nextVideo returns the first unwatched video in the order of the playlist
+func nextVideo(playlist *data.Playlist) *data.Video {
+	playlist.Sort()
+	for idx := range playlist.Videos {
+		if !playlist.Videos[idx].Watched {
+			return &playlist.Videos[idx]
+		}
+	}
+	return nil
+}
+
+func runHelp(ctx *commandContext, args []string) error {
+	w := tabwriter.NewWriter(ctx.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Usage: tubevault [flags] [command] [args]")
+	fmt.Fprintln(w, "Without a command the interactive interface is started.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %s %s\t%s\n", cmd.name, cmd.args, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Every command accepts --json to print machine readable output.")
+	fmt.Fprintln(w, "Run \"tubevault --help\" for the global flags.")
+	return w.Flush()
+}
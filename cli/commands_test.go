@@ -0,0 +1,203 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/cli"
+	"github.com/baumple/watchvault/data"
+)
+
+// newCommandVault returns a config pointing at a temporary vault with two
+// playlists, PL1 with the videos v1 and v2 and PL2 with the video v3
+func newCommandVault(t *testing.T) data.Config {
+	config := data.DefaultConfig()
+	config.VaultPath = t.TempDir()
+
+	dr, err := data.NewJsonRetriever(config.VaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dr.Close()
+
+	published := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	playlists := []data.Playlist{
+		{Id: "PL1", Title: "First", Videos: []data.Video{
+			{Id: "v1", VideoId: "y1", Title: "One", PlaylistId: "PL1", Index: 0, PublishedAt: published},
+			{Id: "v2", VideoId: "y2", Title: "Two", PlaylistId: "PL1", Index: 1, PublishedAt: published.Add(time.Hour)},
+		}},
+		{Id: "PL2", Title: "Second", Videos: []data.Video{
+			{Id: "v3", VideoId: "y3", Title: "Three", PlaylistId: "PL2", Index: 0, PublishedAt: published},
+		}},
+	}
+	for idx := range playlists {
+		err = dr.SavePlaylist(&playlists[idx])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return config
+}
+
+type CommandArgsTest struct {
+	args    []string
+	wantErr bool
+}
+
+var commandArgsTests = []CommandArgsTest{
+	{[]string{"unknown"}, true},
+	{[]string{"list"}, false},
+	{[]string{"list", "--json"}, false},
+	{[]string{"list", "--unknown"}, true},
+	{[]string{"add"}, true},
+	{[]string{"refresh"}, true},
+	{[]string{"remove"}, true},
+	{[]string{"remove", "PL9"}, true},
+	{[]string{"videos"}, true},
+	{[]string{"videos", "PL1", "PL2"}, true},
+	{[]string{"videos", "--json", "PL1", "--unwatched"}, false},
+	{[]string{"mark", "v1"}, true},
+	{[]string{"mark", "v1", "--watched", "--unwatched"}, true},
+	{[]string{"mark", "v9", "--watched"}, true},
+	{[]string{"mark", "v1", "--playlist", "PL2", "--watched"}, true},
+	{[]string{"mark", "y1", "--playlist", "PL1", "--unwatched"}, false},
+	{[]string{"note"}, true},
+	{[]string{"note", "v1", "text", "--clear"}, true},
+	{[]string{"note", "v1"}, false},
+	{[]string{"next", "PL1", "PL2"}, true},
+	{[]string{"next"}, false},
+	{[]string{"next", "PL2"}, false},
+}
+
+func TestCommandArgs(t *testing.T) {
+	config := newCommandVault(t)
+
+	for _, test := range commandArgsTests {
+		err := cli.RunCommand(config, test.args, io.Discard)
+		if (err != nil) != test.wantErr {
+			t.Fatalf("Wanted error %v for %v, got %v", test.wantErr, test.args, err)
+		}
+	}
+}
+
+type CommandJsonTest struct {
+	args []string
+	// expected are the ids in the json output, in order
+	expected []string
+}
+
+// the tests run in order on the same vault
+var commandJsonTests = []CommandJsonTest{
+	{[]string{"list", "--json"}, []string{"PL1", "PL2"}},
+	{[]string{"videos", "PL1", "--json"}, []string{"v1", "v2"}},
+	{[]string{"mark", "--json", "v1", "--watched"}, []string{"v1"}},
+	{[]string{"videos", "PL1", "--unwatched", "--json"}, []string{"v2"}},
+	{[]string{"next", "PL1", "--json"}, []string{"v2"}},
+	{[]string{"next", "--json"}, []string{"v2", "v3"}},
+	{[]string{"note", "v2", "some", "notes", "--json"}, []string{"v2"}},
+	{[]string{"remove", "PL2", "--json"}, []string{"PL2"}},
+	{[]string{"list", "--json"}, []string{"PL1"}},
+}
+
+// commandOutput is the part of the playlist and video summaries the json
+// tests look at
+type commandOutput struct {
+	Id string `json:"id"`
+}
+
+func TestCommandJson(t *testing.T) {
+	config := newCommandVault(t)
+
+	for _, test := range commandJsonTests {
+		out := bytes.Buffer{}
+		err := cli.RunCommand(config, test.args, &out)
+		if err != nil {
+			t.Fatalf("Wanted %v to succeed, got %v", test.args, err)
+		}
+
+		outputs := []commandOutput{}
+		if bytes.HasPrefix(out.Bytes(), []byte("[")) {
+			err = json.Unmarshal(out.Bytes(), &outputs)
+		} else {
+			output := commandOutput{}
+			err = json.Unmarshal(out.Bytes(), &output)
+			outputs = append(outputs, output)
+		}
+		if err != nil {
+			t.Fatalf("Wanted json from %v, got %q: %v", test.args, out.String(), err)
+		}
+
+		ids := []string{}
+		for _, output := range outputs {
+			ids = append(ids, output.Id)
+		}
+		if !slices.Equal(ids, test.expected) {
+			t.Fatalf("Wanted ids %v from %v, got %v", test.expected, test.args, ids)
+		}
+	}
+}
+
+func TestNoteJson(t *testing.T) {
+	config := newCommandVault(t)
+
+	args := []string{"note", "v1", "some", "notes", "--json"}
+	out := bytes.Buffer{}
+	err := cli.RunCommand(config, args, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := data.VideoSummary{}
+	err = json.Unmarshal(out.Bytes(), &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Notes != "some notes" {
+		t.Fatalf("Wanted notes %q, got %q", "some notes", output.Notes)
+	}
+}
+
+type TagTest struct {
+	args     []string
+	expected []string
+}
+
+var tagTests = []TagTest{
+	{[]string{"tag", "PL1", "+a", "b", "+c"}, []string{"a", "b", "c"}},
+	{[]string{"tag", "PL1", "-a"}, []string{"b", "c"}},
+	{[]string{"tag", "--json", "PL1", "+d", "-b"}, []string{"c", "d"}},
+	{[]string{"tag", "PL1", "--", "-c"}, []string{"d"}},
+}
+
+func TestTag(t *testing.T) {
+	config := newCommandVault(t)
+
+	dr, err := data.NewJsonRetriever(config.VaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dr.Close()
+
+	for _, test := range tagTests {
+		err := cli.RunCommand(config, test.args, io.Discard)
+		if err != nil {
+			t.Fatalf("Wanted %v to succeed, got %v", test.args, err)
+		}
+
+		playlists, err := dr.GetPlaylists()
+		if err != nil {
+			t.Fatal(err)
+		}
+		playlist := playlists[slices.IndexFunc(playlists, func(p data.Playlist) bool {
+			return p.Id == "PL1"
+		})]
+		if !slices.Equal(playlist.Tags, test.expected) {
+			t.Fatalf("Wanted tags %v after %v, got %v", test.expected, test.args, playlist.Tags)
+		}
+	}
+}
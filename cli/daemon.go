@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/digest"
+	"github.com/baumple/watchvault/notify"
+)
+
+const (
+	// DAEMON_POLL is the longest time the daemon sleeps before it looks
+	// for new playlists and changed intervals again
+	DAEMON_POLL = time.Minute
+	// DAEMON_RETRY is the time the daemon waits before a failed refresh
+	// is tried again
+	DAEMON_RETRY = 10 * time.Minute
+)
+
+// daemon refreshes the tracked playlists whenever they are due
+type daemon struct {
+	dr       data.DataRetriever
+	yt       *data.YouTubeApi
+	notifier *notify.Notifier
+
+	interval time.Duration
+	jitter   time.Duration
+
+	// jitters holds the random delay of the next refresh of every playlist
+	jitters map[string]time.Duration
+	// retries holds when a failed refresh is tried again
+	retries map[string]time.Time
+	// refresh fetches the new videos of the playlist with the given id
+	// and stores them
+	refresh func(id string) (*data.Playlist, []data.Video, error)
+
+	// config holds the digest settings, the digest is mailed every
+	// config.Digest.Interval if it is set
+	config data.Config
+
+	logger *log.Logger
+}
+
+func newDaemon(dr data.DataRetriever, yt *data.YouTubeApi, notifier *notify.Notifier, interval time.Duration, jitter time.Duration) *daemon {
+	return &daemon{
+		dr:       dr,
+		yt:       yt,
+		notifier: notifier,
+		interval: interval,
+		jitter:   jitter,
+		jitters:  map[string]time.Duration{},
+		retries:  map[string]time.Time{},
+		refresh: func(id string) (*data.Playlist, []data.Video, error) {
+			return data.RefreshPlaylist(dr, yt, id)
+		},
+		logger: log.New(os.Stderr, "tubevault: ", log.LstdFlags),
+	}
+}
+
+// scheduledAt returns when the playlist is refreshed next
+func (d *daemon) scheduledAt(playlist *data.Playlist) time.Time {
+	if retry, ok := d.retries[playlist.Id]; ok {
+		return retry
+	}
+
+	jitter, ok := d.jitters[playlist.Id]
+	if !ok {
+		if d.jitter > 0 {
+			jitter = time.Duration(rand.Int63n(int64(d.jitter)))
+		}
+		d.jitters[playlist.Id] = jitter
+	}
+
+	return playlist.NextRefresh(d.interval).Add(jitter)
+}
+
+// refreshDue refreshes every playlist that is due at now and returns
+// when the next playlist is due. The failed refreshes are logged and
+// returned as well, they are tried again after DAEMON_RETRY.
+func (d *daemon) refreshDue(now time.Time) (time.Time, error) {
+	next := now.Add(DAEMON_POLL)
+
+	playlists, err := d.dr.GetPlaylists()
+	if err != nil {
+		d.logger.Printf("could not read playlists: %v", err)
+		return next, fmt.Errorf("could not read playlists: %w", err)
+	}
+
+	errs := []error{}
+
+	for idx := range playlists {
+		playlist := &playlists[idx]
+
+		at := d.scheduledAt(playlist)
+		if now.Before(at) {
+			if at.Before(next) {
+				next = at
+			}
+			continue
+		}
+
+		updated, newVideos, err := d.refresh(playlist.Id)
+		if err != nil {
+			d.logger.Printf("could not refresh %s: %v", playlist.Title, err)
+			d.retries[playlist.Id] = now.Add(DAEMON_RETRY)
+			errs = append(errs, fmt.Errorf("could not refresh %s: %w", playlist.Title, err))
+			continue
+		}
+
+		delete(d.retries, playlist.Id)
+		delete(d.jitters, playlist.Id)
+		if len(newVideos) > 0 {
+			d.logger.Printf("%s has %d new videos", playlist.Title, len(newVideos))
+		}
+
+		err = d.notifier.Notify(updated, newVideos)
+		if err != nil {
+			d.logger.Print(err)
+		}
+
+		at = d.scheduledAt(updated)
+		if at.Before(next) {
+			next = at
+		}
+	}
+
+	return next, errors.Join(errs...)
+}
+
+// digestDue mails the digest if it is due at now and returns when the
+// next one is due. It returns the zero time if digests are disabled.
+func (d *daemon) digestDue(now time.Time) time.Time {
+	interval := d.config.Digest.Interval.Duration
+	if interval <= 0 {
+		return time.Time{}
+	}
+
+	dir, err := digestStateDir(d.config)
+	if err != nil {
+		d.logger.Printf("could not read the digest state: %v", err)
+		return now.Add(DAEMON_RETRY)
+	}
+
+	state, err := digest.LoadState(dir)
+	if err != nil {
+		d.logger.Printf("could not read the digest state: %v", err)
+		return now.Add(DAEMON_RETRY)
+	}
+
+	// the first digest is sent one interval after the daemon started
+	if state.LastSent.IsZero() {
+		state.LastSent = now
+		err = digest.SaveState(dir, state)
+		if err != nil {
+			d.logger.Printf("could not save the digest state: %v", err)
+		}
+		return now.Add(interval)
+	}
+
+	at := state.LastSent.Add(interval)
+	if now.Before(at) {
+		return at
+	}
+
+	format := d.config.Digest.Format
+	report, body, err := buildDigest(d.dr, state.LastSent, now, format)
+	if err == nil {
+		err = mailDigest(d.config, report, body, format)
+	}
+	if err != nil {
+		d.logger.Printf("could not send the digest: %v", err)
+		return now.Add(DAEMON_RETRY)
+	}
+	d.logger.Printf("sent the digest of %d playlists", len(report.Playlists))
+
+	state.LastSent = now
+	err = digest.SaveState(dir, state)
+	if err != nil {
+		d.logger.Printf("could not save the digest state: %v", err)
+	}
+	return now.Add(interval)
+}
+
+// run refreshes the playlists until ctx is done
+func (d *daemon) run(ctx context.Context) {
+	d.logger.Printf("refreshing playlists every %s (jitter %s)", d.interval, d.jitter)
+	if d.config.Digest.Interval.Duration > 0 {
+		d.logger.Printf("sending a digest every %s", d.config.Digest.Interval)
+	}
+	for {
+		now := time.Now()
+		// failures are logged and retried
+		next, _ := d.refreshDue(now)
+		if at := d.digestDue(now); !at.IsZero() && at.Before(next) {
+			next = at
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.logger.Print("stopping")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func runDaemon(ctx *commandContext, args []string) error {
+	flags := ctx.flags("daemon")
+	once := flags.Bool("once", false, "refresh the due playlists once and exit")
+	interval := flags.String("interval", "", "default refresh interval, overrides the config")
+	jitter := flags.String("jitter", "", "maximum random delay of a refresh, overrides the config")
+	_, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	config := ctx.config
+	if *interval != "" {
+		config.RefreshInterval.Duration, err = data.ParseDuration(*interval)
+		if err != nil {
+			return err
+		}
+	}
+	if *jitter != "" {
+		config.RefreshJitter.Duration, err = data.ParseDuration(*jitter)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.RefreshInterval.Duration <= 0 {
+		return errors.New("the daemon needs a refresh interval greater than 0")
+	}
+
+	err = digest.CheckFormat(config.Digest.Format)
+	if config.Digest.Interval.Duration > 0 && err != nil {
+		return err
+	}
+
+	d := newDaemon(ctx.dr, ctx.yt, ctx.notifier, config.RefreshInterval.Duration, config.RefreshJitter.Duration)
+	d.config = config
+
+	if *once {
+		d.jitter = 0
+		_, err = d.refreshDue(time.Now())
+		d.digestDue(time.Now())
+		return err
+	}
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d.run(signalCtx)
+	return nil
+}
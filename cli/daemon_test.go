@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+// testDaemon returns a daemon over a vault with the given playlists whose
+// refreshes are counted instead of fetched, the ids in failing fail
+func testDaemon(t *testing.T, playlists []data.Playlist, failing map[string]bool) (*daemon, map[string]int) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx := range playlists {
+		err = dr.SavePlaylist(&playlists[idx])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refreshed := map[string]int{}
+	d := newDaemon(dr, nil, nil, time.Hour, 0)
+	d.logger = log.New(io.Discard, "", 0)
+	d.refresh = func(id string) (*data.Playlist, []data.Video, error) {
+		refreshed[id]++
+		if failing[id] {
+			return nil, nil, errors.New("quota exceeded")
+		}
+		playlist, err := data.GetPlaylist(dr, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		playlist.LastRefreshed = time.Now()
+		return playlist, nil, dr.SavePlaylist(playlist)
+	}
+	return d, refreshed
+}
+
+func TestDaemonRefreshDue(t *testing.T) {
+	now := time.Now()
+	d, refreshed := testDaemon(t, []data.Playlist{
+		{Id: "new"},
+		{Id: "due", LastRefreshed: now.Add(-2 * time.Hour)},
+		{Id: "soon", LastRefreshed: now.Add(-time.Hour + 30*time.Second)},
+		{Id: "later", LastRefreshed: now.Add(-time.Minute)},
+	}, nil)
+
+	next, err := d.refreshDue(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed["new"] != 1 || refreshed["due"] != 1 || refreshed["soon"] != 0 || refreshed["later"] != 0 {
+		t.Fatalf("Wanted only the due playlists to be refreshed, got %v", refreshed)
+	}
+	if !next.Equal(now.Add(30 * time.Second)) {
+		t.Fatalf("Wanted the next refresh in 30s, got %v", next.Sub(now))
+	}
+}
+
+func TestDaemonRetry(t *testing.T) {
+	now := time.Now()
+	d, refreshed := testDaemon(t, []data.Playlist{{Id: "a"}, {Id: "b"}}, map[string]bool{"a": true, "b": true})
+
+	_, err := d.refreshDue(now)
+	if err == nil {
+		t.Fatal("Wanted the failed refreshes to be returned")
+	}
+
+	_, err = d.refreshDue(now.Add(DAEMON_RETRY - time.Second))
+	if err != nil || refreshed["a"] != 1 {
+		t.Fatalf("Wanted no retry before %v, got %d refreshes (%v)", DAEMON_RETRY, refreshed["a"], err)
+	}
+
+	_, err = d.refreshDue(now.Add(DAEMON_RETRY))
+	if err == nil || refreshed["a"] != 2 || refreshed["b"] != 2 {
+		t.Fatalf("Wanted a retry after %v, got %v", DAEMON_RETRY, refreshed)
+	}
+}
+
+func TestDaemonJitter(t *testing.T) {
+	lastRefreshed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	playlist := data.Playlist{Id: "a", LastRefreshed: lastRefreshed}
+
+	d := newDaemon(nil, nil, nil, time.Hour, 5*time.Minute)
+	at := d.scheduledAt(&playlist)
+	if at.Before(lastRefreshed.Add(time.Hour)) || !at.Before(lastRefreshed.Add(time.Hour+5*time.Minute)) {
+		t.Fatalf("Wanted the refresh within the jitter after the interval, got %v", at)
+	}
+	if again := d.scheduledAt(&playlist); !again.Equal(at) {
+		t.Fatalf("Wanted the jitter to stay the same, got %v and %v", at, again)
+	}
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/digest"
+	"github.com/baumple/watchvault/notify"
+)
+
+// digestStateDir returns the directory the digest state is stored in,
+// next to the playlists of the vault
+func digestStateDir(config data.Config) (string, error) {
+	if config.VaultPath != "" {
+		return config.VaultPath, nil
+	}
+	return data.GetSaveDirPath()
+}
+
+// buildDigest renders the digest of the time between since and until
+func buildDigest(dr data.DataRetriever, since time.Time, until time.Time, format string) (digest.Report, string, error) {
+	playlists, err := dr.GetPlaylists()
+	if err != nil {
+		return digest.Report{}, "", err
+	}
+
+	report := digest.New(playlists, since, until)
+	body := bytes.Buffer{}
+	err = report.Render(&body, format)
+	return report, body.String(), err
+}
+
+// mailDigest sends a rendered digest through the configured smtp server
+func mailDigest(config data.Config, report digest.Report, body string, format string) error {
+	smtp, err := config.DigestSmtp()
+	if err != nil {
+		return err
+	}
+	return notify.Mail(smtp, report.Subject(), body, digest.ContentType(format))
+}
+
+func runDigest(ctx *commandContext, args []string) error {
+	flags := ctx.flags("digest")
+	since := flags.String("since", "7d", "report the changes of this duration")
+	format := flags.String("format", ctx.config.Digest.Format, "md, html or text")
+	out := flags.String("out", "", "write the report to this file instead of stdout")
+	mail := flags.Bool("mail", false, "send the report through the configured smtp server")
+	_, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	err = digest.CheckFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	duration, err := data.ParseDuration(*since)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	report, body, err := buildDigest(ctx.dr, now.Add(-duration), now, *format)
+	if err != nil {
+		return err
+	}
+
+	if *mail {
+		err = mailDigest(ctx.config, report, body, *format)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *out != "" {
+		return os.WriteFile(*out, []byte(body), data.FILE_PERMISSIONS)
+	}
+	if ctx.json {
+		return ctx.print(report, nil)
+	}
+	if *mail {
+		return nil
+	}
+
+	// the report is written as is, the tabwriter of print would align
+	// tabs in titles
+	_, err = io.WriteString(ctx.out, body)
+	return err
+}
@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/opener"
+	"github.com/baumple/watchvault/thumbnail"
+	"github.com/baumple/watchvault/utility"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// INBOX_PLAYLIST_WIDTH is the widest the playlist column of the inbox gets
+const INBOX_PLAYLIST_WIDTH = 24
+
+// inboxEntry is an unwatched video and the playlist it is in
+type inboxEntry struct {
+	playlist *data.Playlist
+	video    *data.Video
+}
+
+// inboxModel lists the unwatched videos of every tracked playlist, the
+// newest first
+type inboxModel struct {
+	width  int
+	height int
+
+	entries []inboxEntry
+	cursor  int
+
+	visualMode  bool
+	visualStart int
+
+	dr     data.DataRetriever
+	keys   keymap
+	theme  theme
+	opener string
+	// newWindow is how long found videos are new, see data.Video.IsNew
+	newWindow time.Duration
+
+	thumbnails        *thumbnail.Cache
+	thumbnailProtocol string
+	// output is the output of the program, see videoModel.output
+	output io.Writer
+
+	// reload reads the playlists again once the inbox is closed
+	reload tea.Cmd
+
+	currentModel tea.Model
+}
+
+// newInboxModel collects the unwatched videos of the playlists. It works
+// on copies, a refresh may replace the playlists while the inbox is open.
+func newInboxModel(playlists []data.Playlist) inboxModel {
+	copies := make([]data.Playlist, len(playlists))
+	entries := []inboxEntry{}
+	for pidx := range playlists {
+		copies[pidx] = playlists[pidx]
+		copies[pidx].Videos = slices.Clone(playlists[pidx].Videos)
+
+		playlist := &copies[pidx]
+		for vidx := range playlist.Videos {
+			if !playlist.Videos[vidx].Watched {
+				entries = append(entries, inboxEntry{playlist, &playlist.Videos[vidx]})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].video.PublishedAt.After(entries[j].video.PublishedAt)
+	})
+	return inboxModel{entries: entries}
+}
+
+func (i inboxModel) Init() tea.Cmd {
+	return nil
+}
+
+func (i inboxModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		i.width = msg.Width
+		i.height = msg.Height
+		return i, i.resize()
+	}
+
+	if i.currentModel != nil {
+		model, cmd := i.currentModel.Update(msg)
+		i.currentModel = model
+		return i, cmd
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return i, nil
+	}
+
+	switch key := keyMsg.String(); {
+	case i.keys.is(key, ACTION_QUIT):
+		return i, tea.Quit
+
+	case i.keys.is(key, ACTION_BACK):
+		if !i.visualMode {
+			return nil, i.reload
+		}
+		i.visualMode = false
+
+	case i.keys.is(key, ACTION_DOWN):
+		i.cursor = max(min(i.cursor+1, len(i.entries)-1), 0)
+	case i.keys.is(key, ACTION_UP):
+		i.cursor = max(i.cursor-1, 0)
+	case i.keys.is(key, ACTION_PAGE_DOWN):
+		i.cursor = max(min(i.cursor+i.rowsPerPage(), len(i.entries)-1), 0)
+	case i.keys.is(key, ACTION_PAGE_UP):
+		i.cursor = max(i.cursor-i.rowsPerPage(), 0)
+	case i.keys.is(key, ACTION_BOTTOM):
+		i.cursor = max(len(i.entries)-1, 0)
+	case i.keys.is(key, ACTION_TOP):
+		i.cursor = 0
+
+	case i.keys.is(key, ACTION_VISUAL):
+		i.visualMode = !i.visualMode
+
+	case i.keys.is(key, ACTION_TOGGLE_WATCHED):
+		if len(i.entries) == 0 {
+			break
+		}
+		start, end := i.selection()
+		i.visualMode = false
+
+		// the videos stay listed until the inbox is opened again, so a
+		// mistake can be undone
+		toggled := []inboxEntry{}
+		for idx := start; idx < end; idx++ {
+			entry := i.entries[idx]
+			entry.video.SetWatched(!entry.video.Watched)
+			toggled = append(toggled, entry)
+		}
+
+		dr := i.dr
+		i.visualStart = i.cursor
+		return i, write("could not save the watched state", func() error {
+			for _, entry := range toggled {
+				err := dr.UpdateVideoWatched(entry.playlist.Id, entry.video.Id, entry.video.Watched)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case i.keys.is(key, ACTION_OPEN_VIDEO):
+		if len(i.entries) == 0 {
+			break
+		}
+		entry := i.entries[i.cursor]
+		return i, open(opener.Resolve(i.opener, entry.playlist), opener.ForVideo(entry.video))
+
+	case i.keys.is(key, ACTION_DETAILS):
+		if len(i.entries) == 0 {
+			break
+		}
+		entry := i.entries[i.cursor]
+		videoModel := newVideoModel(entry.video, i.width, i.height)
+		videoModel.dr = i.dr
+		videoModel.keys = i.keys
+		videoModel.theme = i.theme
+		videoModel.opener = opener.Resolve(i.opener, entry.playlist)
+		videoModel.thumbnails = i.thumbnails
+		videoModel.thumbnailProtocol = i.thumbnailProtocol
+		videoModel.output = i.output
+		i.currentModel = videoModel
+		return i, tea.Batch(videoModel.Init(), i.resize())
+	}
+
+	if !i.visualMode {
+		i.visualStart = i.cursor
+	}
+	return i, nil
+}
+
+// selection returns the range of the selected entries
+func (i inboxModel) selection() (int, int) {
+	return min(i.visualStart, i.cursor), max(i.visualStart, i.cursor) + 1
+}
+
+// split reports whether the video details are shown next to the list
+func (i inboxModel) split() bool {
+	return i.width >= DETAIL_SPLIT_WIDTH
+}
+
+// listWidth returns the width of the list of videos
+func (i inboxModel) listWidth() int {
+	if i.split() {
+		return i.width / 2
+	}
+	return i.width
+}
+
+// resize gives the video details the size of their pane
+func (i *inboxModel) resize() tea.Cmd {
+	width, column := i.width, 0
+	if i.split() {
+		width = i.width - i.listWidth()
+		column = i.listWidth()
+	}
+	model, cmd := resizeDetails(i.currentModel, width, i.height, column)
+	i.currentModel = model
+	return cmd
+}
+
+// rowsPerPage returns the number of videos that fit on the screen
+func (i inboxModel) rowsPerPage() int {
+	return max(i.height-strings.Count(i.keymaps(i.listWidth()), "\n")-4, 3)
+}
+
+func (i inboxModel) View() string {
+	if !i.split() {
+		if i.currentModel != nil {
+			return i.currentModel.View()
+		}
+		return i.view(i.width)
+	}
+
+	detailWidth := i.width - i.listWidth()
+	var video *data.Video
+	if len(i.entries) > 0 {
+		video = i.entries[i.cursor].video
+	}
+	detail := previewVideo(video, detailWidth, i.height, i.keys, i.theme)
+	if i.currentModel != nil {
+		detail = i.currentModel.View()
+	}
+	return joinPanes(i.height, pane{i.view(i.listWidth()), i.listWidth()}, pane{detail, detailWidth})
+}
+
+// view renders the list of videos in the given width
+func (i inboxModel) view(width int) string {
+	playlists := map[string]bool{}
+	playlistWidth := 0
+	for _, entry := range i.entries {
+		playlists[entry.playlist.Id] = true
+		playlistWidth = max(playlistWidth, utility.Width(entry.playlist.Title))
+	}
+	playlistWidth = min(playlistWidth, INBOX_PLAYLIST_WIDTH, width/3)
+
+	text := makeTobBarTitle(fmt.Sprintf("Inbox (%d unwatched videos in %d playlists)",
+		len(i.entries), len(playlists)), width)
+	if len(i.entries) == 0 {
+		text += makeLine(" nothing to watch", width)
+	}
+
+	now := time.Now()
+	start, end := i.selection()
+	rows := i.rowsPerPage()
+	window := GetWindow(i.cursor, rows)
+	for idx := window.Start; idx < window.End && len(i.entries) > 0; idx++ {
+		if idx >= len(i.entries) {
+			text += makeLine("", width)
+			continue
+		}
+		entry := i.entries[idx]
+
+		cursor := " "
+		if idx == i.cursor {
+			cursor = ">"
+		}
+		watched := "[ ]"
+		if entry.video.Watched {
+			watched = "[X]"
+		}
+		modifier := ""
+		if i.visualMode && idx >= start && idx < end {
+			modifier = i.theme.selection
+		}
+		newText := "     "
+		if entry.video.IsNew(entry.playlist.LastViewed, i.newWindow, now) {
+			newText = i.theme.highlight + ">NEW<" + RESET + modifier
+		}
+
+		text += makeLine(fmt.Sprintf("%s %s %s %s %s  %s  %s%s",
+			modifier,
+			cursor,
+			watched,
+			newText,
+			entry.video.PublishedAt.Local().Format("2006-01-02"),
+			utility.Pad(entry.playlist.Title, playlistWidth),
+			entry.video.Title,
+			RESET,
+		), width)
+	}
+	text += makeBottomBar(width)
+
+	return text + i.keymaps(width)
+}
+
+// keymaps returns the box with the keys of the inbox
+func (i inboxModel) keymaps(width int) string {
+	text := makeTobBarTitle("Keymaps", width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> return", i.keys.help(ACTION_BACK)), width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> toggle watched", i.keys.help(ACTION_TOGGLE_WATCHED)), width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> visual mode", i.keys.help(ACTION_VISUAL)), width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> video details", i.keys.help(ACTION_DETAILS)), width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> open video", i.keys.help(ACTION_OPEN_VIDEO)), width)
+	text += makeBottomBar(width)
+	return text
+}
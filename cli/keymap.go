@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// actions that can be bound to keys in the Keymap section of the config
+const (
+	ACTION_UP             = "up"
+	ACTION_DOWN           = "down"
+	ACTION_TOP            = "top"
+	ACTION_BOTTOM         = "bottom"
+	ACTION_PAGE_UP        = "page_up"
+	ACTION_PAGE_DOWN      = "page_down"
+	ACTION_QUIT           = "quit"
+	ACTION_BACK           = "back"
+	ACTION_REMOVE         = "remove"
+	ACTION_SEARCH         = "search"
+	ACTION_OPEN           = "open"
+	ACTION_OPEN_VIDEO     = "open_video"
+	ACTION_SELECT         = "select"
+	ACTION_DETAILS        = "details"
+	ACTION_TOGGLE_WATCHED = "toggle_watched"
+	ACTION_VISUAL         = "visual"
+	ACTION_PLAY           = "play"
+	ACTION_QUEUE          = "queue"
+	ACTION_DOWNLOAD       = "download"
+	ACTION_FOCUS          = "focus"
+	ACTION_FILTER         = "filter"
+	ACTION_SORT           = "sort"
+	ACTION_REVERSE        = "reverse"
+	ACTION_HIDE_WATCHED   = "hide_watched"
+	ACTION_ONLY_NEW       = "only_new"
+	ACTION_IN_PROGRESS    = "in_progress"
+	ACTION_WITH_NOTES     = "with_notes"
+	ACTION_RETRY          = "retry"
+	ACTION_REFRESH        = "refresh"
+	ACTION_INBOX          = "inbox"
+)
+
+var defaultKeys = map[string][]string{
+	ACTION_UP:             {"up", "k"},
+	ACTION_DOWN:           {"down", "j"},
+	ACTION_TOP:            {"g"},
+	ACTION_BOTTOM:         {"G"},
+	ACTION_PAGE_UP:        {"ctrl+u"},
+	ACTION_PAGE_DOWN:      {"ctrl+d"},
+	ACTION_QUIT:           {"q", "ctrl+c"},
+	ACTION_BACK:           {"esc"},
+	ACTION_REMOVE:         {"f5"},
+	ACTION_SEARCH:         {"s"},
+	ACTION_OPEN:           {"enter"},
+	ACTION_OPEN_VIDEO:     {"o"},
+	ACTION_SELECT:         {" "},
+	ACTION_DETAILS:        {"enter"},
+	ACTION_TOGGLE_WATCHED: {" "},
+	ACTION_VISUAL:         {"v"},
+	ACTION_PLAY:           {"p"},
+	ACTION_QUEUE:          {"P"},
+	ACTION_DOWNLOAD:       {"d"},
+	ACTION_FOCUS:          {"tab"},
+	ACTION_FILTER:         {"/"},
+	ACTION_SORT:           {"S"},
+	ACTION_REVERSE:        {"R"},
+	ACTION_HIDE_WATCHED:   {"h"},
+	ACTION_ONLY_NEW:       {"u"},
+	ACTION_IN_PROGRESS:    {"i"},
+	ACTION_WITH_NOTES:     {"m"},
+	ACTION_RETRY:          {"ctrl+r"},
+	ACTION_REFRESH:        {"r"},
+	ACTION_INBOX:          {"i"},
+}
+
+// keymap maps actions to the keys that trigger them
+type keymap map[string][]string
+
+// newKeymap returns the default keymap with the given overrides applied.
+// overrides maps an action to a comma separated list of keys.
+func newKeymap(overrides map[string]string) (keymap, error) {
+	keys := keymap{}
+	for action, bound := range defaultKeys {
+		keys[action] = bound
+	}
+
+	for action, bound := range overrides {
+		if _, ok := defaultKeys[action]; !ok {
+			return nil, fmt.Errorf("unknown action %q in keymap", action)
+		}
+
+		keys[action] = []string{}
+		for _, key := range strings.Split(bound, ",") {
+			key = strings.TrimSpace(key)
+			if key == "space" {
+				key = " "
+			}
+			if key != "" {
+				keys[action] = append(keys[action], key)
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// is returns whether key is bound to action
+func (k keymap) is(key string, action string) bool {
+	for _, bound := range k[action] {
+		if bound == key {
+			return true
+		}
+	}
+	return false
+}
+
+// help returns the keys of action formatted for the keymap help,
+// e.g. "<q>"
+func (k keymap) help(action string) string {
+	if len(k[action]) == 0 {
+		return "<>"
+	}
+
+	key := k[action][0]
+	if key == " " {
+		key = "space"
+	}
+	return "<" + key + ">"
+}
@@ -1,12 +1,21 @@
 package cli
 
 import (
+	"cmp"
+	"context"
 	"fmt"
-	"log"
-	"os/exec"
+	"io"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/download"
+	"github.com/baumple/watchvault/mpv"
+	"github.com/baumple/watchvault/notify"
+	"github.com/baumple/watchvault/opener"
+	"github.com/baumple/watchvault/thumbnail"
+	"github.com/baumple/watchvault/utility"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -20,6 +29,66 @@ type msgListUpdated struct {
 	playlists []data.Playlist
 }
 
+// msgRefreshTick is sent every refresh interval to fetch playlist updates
+type msgRefreshTick struct{}
+
+// msgPlaybackDone is sent when mpv exits, the playlist changed in the vault
+type msgPlaybackDone struct {
+	playlistId string
+	err        error
+}
+
+// play plays videos in mpv and reports when it exits
+func play(player *mpv.Player, playlistId string, videos []data.Video) tea.Cmd {
+	return func() tea.Msg {
+		_, err := player.Play(videos)
+		return msgPlaybackDone{playlistId, err}
+	}
+}
+
+// DOWNLOAD_REFRESH is how often the progress of running downloads is shown
+const DOWNLOAD_REFRESH = time.Second
+
+// msgDownloadsQueued is sent when videos were marked for download
+type msgDownloadsQueued struct {
+	err error
+}
+
+// msgDownloadsDone is sent when the download queue is empty
+type msgDownloadsDone struct {
+	err error
+}
+
+// msgDownloadTick reloads the playlists while downloads run
+type msgDownloadTick struct{}
+
+// queueDownloads marks the videos with the given ids for download, all
+// videos of the playlist without ids
+func queueDownloads(dr data.DataRetriever, playlistId string, ids ...string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := download.Queue(dr, playlistId, ids...)
+		return msgDownloadsQueued{err}
+	}
+}
+
+// cancelDownloads removes the videos with the given ids from the queue
+func cancelDownloads(dr data.DataRetriever, playlistId string, ids ...string) tea.Cmd {
+	return func() tea.Msg {
+		return msgDownloadsQueued{download.Cancel(dr, playlistId, ids...)}
+	}
+}
+
+// open runs the opener for target and reports a failure as msgStatus
+func open(command string, target opener.Target) tea.Cmd {
+	return func() tea.Msg {
+		err := opener.Open(command, target)
+		if err != nil {
+			return errorStatus("could not open "+target.Url, err, nil)
+		}
+		return nil
+	}
+}
+
 type mainModel struct {
 	width  int
 	height int
@@ -31,88 +100,218 @@ type mainModel struct {
 
 	dr data.DataRetriever
 	yt data.YouTubeApi
+
+	notifier *notify.Notifier
+
+	keys            keymap
+	theme           theme
+	opener          string
+	refreshInterval time.Duration
+	// newWindow is how long found videos are new, see data.Video.IsNew
+	newWindow  time.Duration
+	player     *mpv.Player
+	downloader *download.Manager
+
+	thumbnails        *thumbnail.Cache
+	thumbnailProtocol string
+	// output is the output of the program, see videoModel.output
+	output io.Writer
+
+	// downloading is set while the downloader runs
+	downloading bool
+
+	// status is shown below the current view, statusId tells it apart
+	// from the ones before
+	status   msgStatus
+	statusId int
+
+	// listSort is one of the LIST_SORT_* orders of the tracked playlists
+	listSort           string
+	listSortDescending bool
+
+	// refreshing holds the progress of the running refreshes
+	refreshing *refreshState
 }
 
 func initialModel() mainModel {
-	return mainModel{}
+	return mainModel{refreshing: newRefreshState()}
 }
 
 func (s mainModel) Init() tea.Cmd {
+	return tea.Sequence(s.loadPlaylists(), s.resumeDownloads(), s.refreshDue(), s.scheduleRefresh())
+}
+
+// resumeDownloads continues the downloads of the last session
+func (s mainModel) resumeDownloads() tea.Cmd {
 	return func() tea.Msg {
-		playlists, err := s.dr.GetPlaylists()
-		for idx := range playlists {
-                        playlists[idx].FetchUpdate(&s.yt)
-                        if playlists[idx].Updated {
-                                s.dr.SavePlaylist(&playlists[idx])
-                        }
+		pending, err := download.Pending(s.dr)
+		if err != nil || len(pending) == 0 {
+			return nil
 		}
+		return msgDownloadsQueued{}
+	}
+}
+
+// loadPlaylists reads the stored playlists without fetching updates
+func (s mainModel) loadPlaylists() tea.Cmd {
+	return func() tea.Msg {
+		playlists, err := s.dr.GetPlaylists()
 		if err != nil {
-			log.Fatal(err)
+			return errorStatus("could not load the playlists", err, s.loadPlaylists())
 		}
-
 		return msgListUpdated{playlists}
 	}
 }
 
+// scheduleRefresh sends a msgRefreshTick after the refresh interval.
+// A refresh interval of 0 disables refreshing.
+func (s mainModel) scheduleRefresh() tea.Cmd {
+	if s.refreshInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(s.refreshInterval, func(time.Time) tea.Msg {
+		return msgRefreshTick{}
+	})
+}
+
 func (s mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case msgRefreshTick:
+		return s, tea.Batch(s.refreshDue(), s.scheduleRefresh())
+	case msgRefresh:
+		return s, s.refresh(msg.ids)
+	case msgRefreshProgress:
+		if msg.done && msg.err == nil && s.currentModel != nil {
+			// the open playlist keeps its cursor on the same video
+			model, _ := s.currentModel.Update(msg)
+			s.currentModel = model
+		}
+		return s, s.refreshed(msg)
+	case msgSpinnerTick:
+		return s, s.spin()
+	case msgStatus:
+		return s, s.showStatus(msg)
+	case msgStatusTimeout:
+		if msg.id == s.statusId {
+			s.status = msgStatus{}
+		}
+		return s, nil
+	case msgDownloadsQueued:
+		reload := s.reloadPlaylists()
+		if msg.err != nil {
+			return s, tea.Batch(reload, reportError("could not queue the download", msg.err))
+		}
+		if s.downloading {
+			return s, reload
+		}
+		s.downloading = true
+		return s, tea.Batch(reload, s.runDownloads(), s.downloadTick())
+	case msgDownloadTick:
+		reload := s.reloadPlaylists()
+		if !s.downloading {
+			return s, reload
+		}
+		return s, tea.Batch(reload, s.downloadTick())
+	case msgDownloadsDone:
+		s.downloading = false
+		reload := s.reloadPlaylists()
+		if msg.err != nil {
+			return s, tea.Batch(reload, reportError("downloads stopped", msg.err))
+		}
+		return s, reload
+	case msgPlaybackDone:
+		reload := s.reloadPlaylist(msg.playlistId)
+		if msg.err != nil {
+			return s, tea.Batch(reload, reportError("playback failed", msg.err))
+		}
+		return s, reload
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+		s.height = msg.Height
+		return s, s.resize()
+	case tea.KeyMsg:
+		if s.keys.is(msg.String(), ACTION_RETRY) && s.status.retry != nil {
+			retry := s.status.retry
+			s.status = msgStatus{}
+			return s, retry
+		}
+		// errors stay until they time out so they can be retried
+		if s.status.severity != SEVERITY_ERROR {
+			s.status = msgStatus{}
+		}
+	}
+
 	if s.currentModel != nil {
 		model, cmd := s.currentModel.Update(msg)
 		s.currentModel = model
+		if model == nil {
+			// refreshes while the playlist was open could not reorder it
+			s.sortPlaylists()
+		}
 		return s, cmd
 	}
 
 	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		s.width = msg.Width
-		s.height = msg.Height
-
 	case tea.KeyMsg:
 		return s.HandleInput(msg.String())
 
 	case msgListUpdated:
+		selected := ""
+		if s.cursor < len(s.trackedPlaylists) {
+			selected = s.trackedPlaylists[s.cursor].Id
+		}
+
 		s.trackedPlaylists = msg.playlists
-		s.cursor = 0
+		// the preview shows the videos in the order of the playlist
+		for idx := range s.trackedPlaylists {
+			s.trackedPlaylists[idx].Sort()
+		}
+		// the cursor stays on the same playlist or where it was if that
+		// playlist is gone
+		s.cursor = max(min(s.cursor, len(s.trackedPlaylists)-1), 0)
+		for idx := range s.trackedPlaylists {
+			if s.trackedPlaylists[idx].Id == selected {
+				s.cursor = idx
+			}
+		}
+		s.sortPlaylists()
 
 	case msgSearchedResult:
 		playlist := msg.playlist
 
-		s.addPlaylist(playlist)
-
 		s.currentModel = nil
+		return s, s.addPlaylist(playlist)
 	}
 	return s, nil
 }
 
 func (s *mainModel) HandleInput(key string) (tea.Model, tea.Cmd) {
-	switch key {
-
-	case "up", "k":
+	switch {
+	case s.keys.is(key, ACTION_UP):
 		if s.cursor > 0 {
 			s.cursor--
 		}
-	case "down", "j":
+	case s.keys.is(key, ACTION_DOWN):
 		if s.cursor < len(s.trackedPlaylists)-1 {
 			s.cursor++
 		}
 
-	case tea.KeyF5.String():
+	case s.keys.is(key, ACTION_REMOVE):
 		if len(s.trackedPlaylists) <= 0 {
 			break
 		}
-		return s, func() tea.Msg {
-			err := s.dr.DeletePlaylist(s.trackedPlaylists[s.cursor].Id)
+		id := s.trackedPlaylists[s.cursor].Id
+		var remove tea.Cmd
+		remove = func() tea.Msg {
+			err := s.dr.DeletePlaylist(id)
 			if err != nil {
-				log.Fatal(err)
+				return errorStatus("could not remove the playlist", err, remove)
 			}
-			playlists, err := s.dr.GetPlaylists()
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			return msgListUpdated{playlists}
+			return s.loadPlaylists()()
 		}
+		return s, remove
 
-	case "s":
+	case s.keys.is(key, ACTION_SEARCH):
 		searchModel := searchModel{
 			foundPlaylists: []data.Playlist{},
 			cursor:         0,
@@ -120,33 +319,79 @@ func (s *mainModel) HandleInput(key string) (tea.Model, tea.Cmd) {
 			yt:             &s.yt,
 			dr:             s.dr,
 			searchFocused:  true,
+			width:          s.width,
 		}
 		s.currentModel = searchModel
 
-	case "enter":
+	case s.keys.is(key, ACTION_OPEN):
 		if len(s.trackedPlaylists) <= 0 {
 			break
 		}
-		return s, func() tea.Msg {
-			_, err := exec.Command("firefox",
-				"https://youtube.com/playlist?list="+s.trackedPlaylists[s.cursor].
-					Id).
-				Output()
-			if err != nil {
-				return err
-			}
-			return nil
+		playlist := &s.trackedPlaylists[s.cursor]
+		return s, open(opener.Resolve(s.opener, playlist), opener.ForPlaylist(playlist))
+
+	case s.keys.is(key, ACTION_DOWNLOAD):
+		if len(s.trackedPlaylists) <= 0 {
+			break
 		}
+		return s, queueDownloads(s.dr, s.trackedPlaylists[s.cursor].Id)
 
-	case " ":
+	case s.keys.is(key, ACTION_SELECT):
 		if len(s.trackedPlaylists) <= 0 {
 			break
 		}
-		playlistModel := NewPlaylistModel(s.dr, s.width, s.height, &s.trackedPlaylists[s.cursor])
-		s.currentModel = playlistModel
-		return s, nil
+		playlist := &s.trackedPlaylists[s.cursor]
+		playlist.Sort()
+		s.currentModel = s.playlistModel(playlist)
+		resize := s.resize()
+
+		// the new videos have been seen now
+		playlist.Updated = false
+		playlist.LastViewed = time.Now()
+		id, viewed := playlist.Id, playlist.LastViewed
+		return s, tea.Batch(resize, write("could not save the playlist", func() error {
+			return s.dr.UpdatePlaylistViewed(id, viewed)
+		}))
+
+	case s.keys.is(key, ACTION_INBOX):
+		inboxModel := newInboxModel(s.trackedPlaylists)
+		inboxModel.dr = s.dr
+		inboxModel.keys = s.keys
+		inboxModel.theme = s.theme
+		inboxModel.opener = s.opener
+		inboxModel.newWindow = s.newWindow
+		inboxModel.thumbnails = s.thumbnails
+		inboxModel.thumbnailProtocol = s.thumbnailProtocol
+		inboxModel.output = s.output
+		inboxModel.reload = s.loadPlaylists()
+		s.currentModel = inboxModel
+		return s, s.resize()
+
+	case s.keys.is(key, ACTION_REFRESH):
+		ids := []string{}
+		for idx := range s.trackedPlaylists {
+			ids = append(ids, s.trackedPlaylists[idx].Id)
+		}
+		return s, s.refresh(ids)
+
+	case s.keys.is(key, ACTION_SORT):
+		next := LIST_SORT_MODES[0]
+		for idx, mode := range LIST_SORT_MODES {
+			if mode == s.listSort {
+				next = LIST_SORT_MODES[(idx+1)%len(LIST_SORT_MODES)]
+			}
+		}
+		s.listSort = next
+		s.sortPlaylists()
+
+	case s.keys.is(key, ACTION_REVERSE):
+		s.listSortDescending = !s.listSortDescending
+		if s.listSort == "" {
+			s.listSort = LIST_SORT_TITLE
+		}
+		s.sortPlaylists()
 
-	case "q", "esc":
+	case s.keys.is(key, ACTION_QUIT), s.keys.is(key, ACTION_BACK):
 		return s, tea.Quit
 	}
 	return s, nil
@@ -159,64 +404,339 @@ func (s mainModel) View() string {
 		return ""
 	}
 
+	if s.status.text != "" {
+		return s.view() + s.statusLine() + "\n"
+	}
+	return s.view()
+}
+
+// view renders the current model without the status
+func (s mainModel) view() string {
+	_, playlistOpen := s.currentModel.(playlistModel)
+	if s.split() && (s.currentModel == nil || playlistOpen) {
+		// the selected playlist is shown while the list has the focus
+		playlist := s.preview()
+		if s.currentModel != nil {
+			playlist = s.currentModel.View()
+		}
+		return joinPanes(s.height-1,
+			pane{s.listPane(), s.listWidth()},
+			pane{playlist, s.width - s.listWidth()})
+	}
+
 	if s.currentModel != nil {
 		return s.currentModel.View()
 	}
-	text := "Tracked playlists:\n\n"
+	text := fmt.Sprintf("Tracked playlists (sorted by %s)%s:\n\n", s.listSortLabel(), s.refreshSummary())
 
-	maxLenTitle := 0
+	maxLenTitle := len("Name:")
+	maxLenChannel := len("Channel:")
 	for _, playlist := range s.trackedPlaylists {
-		maxLenTitle = max(len(playlist.Title), maxLenTitle)
+		maxLenTitle = max(utility.Width(playlist.Title), maxLenTitle)
+		maxLenChannel = max(utility.Width(playlist.ChannelName()), maxLenChannel)
 	}
+	maxLenChannel = min(maxLenChannel, MAX_LEN_CHANNEL)
 
-	text += "Name:" + strings.Repeat(" ", maxLenTitle) + "Description:\n"
-	for i := 0; i < s.width; i++ {
-		borderChar := "─"
-		if i == maxLenTitle+7 {
-			borderChar = "┬"
+	columns := "  %-*s   │ %-25s │ %-7s │ %-3s │ %-11s │ %-*s │ Description:"
+	// long titles are cut to the width the other columns leave, Pad
+	// truncates them
+	fixedWidth := utility.Width(fmt.Sprintf(columns,
+		0, "", "Watched:", "Left:", "New", "Refreshed", maxLenChannel, "Channel:"))
+	maxLenTitle = max(min(maxLenTitle, s.width-fixedWidth), len("Name:"))
+
+	header := fmt.Sprintf(columns,
+		maxLenTitle, "Name:", "Watched:", "Left:", "New", "Refreshed", maxLenChannel, "Channel:")
+	text += header + "\n"
+	text += makeColumnBorder(header, "┬", s.width)
+
+	now := time.Now()
+	for i := range s.trackedPlaylists {
+		playlist := &s.trackedPlaylists[i]
+
+		cursor := " "
+		if s.cursor == i {
+			cursor = ">"
 		}
-		text += borderChar
+
+		updatedText := " "
+		if playlist.Updated {
+			updatedText = s.theme.highlight + "*" + RESET
+		}
+
+		newText := "   "
+		if count := playlist.NewCount(s.newWindow, now); count > 0 {
+			newText = s.theme.highlight + fmt.Sprintf("%3d", count) + RESET
+		}
+
+		line := fmt.Sprintf(
+			"%s %s %s │ %s │ %7s │ %s │ %11s │ %s │ ",
+			cursor,
+			utility.Pad(playlist.Title, maxLenTitle),
+			updatedText,
+			progressColumn(playlist),
+			formatRemaining(playlist),
+			newText,
+			s.refreshColumn(playlist, now),
+			utility.Pad(playlist.ChannelName(), maxLenChannel),
+		)
+		// the description fills the rest of the line
+		descriptionWidth := max(s.width-utility.Width(header)+len("Description:")-1, 0)
+		description := strings.ReplaceAll(playlist.Description, "\n", " ")
+		text += line + utility.Truncate(description, descriptionWidth) + "\n"
+	}
+
+	text += makeColumnBorder(header, "┴", s.width)
+	text += s.keymaps(s.width)
+
+	return text
+}
+
+// keymaps returns the box with the keys of the playlist list
+func (s mainModel) keymaps(width int) string {
+	text := makeTobBarTitle("Keymaps", width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> quit", s.keys.help(ACTION_QUIT)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> remove playlist", s.keys.help(ACTION_REMOVE)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> search playlist", s.keys.help(ACTION_SEARCH)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> open playlist", s.keys.help(ACTION_OPEN)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> view playlist", s.keys.help(ACTION_SELECT)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> download playlist", s.keys.help(ACTION_DOWNLOAD)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> refresh all playlists", s.keys.help(ACTION_REFRESH)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> unwatched videos of all playlists", s.keys.help(ACTION_INBOX)), width)
+	text += makeLine(fmt.Sprintf(" * %-7s -> change/reverse the order", s.keys.help(ACTION_SORT)+"/"+s.keys.help(ACTION_REVERSE)), width)
+	text += makeBottomBar(width)
+	return text
+}
+
+// SPLIT_WIDTH is the narrowest terminal that shows the playlists next to
+// the open playlist
+const SPLIT_WIDTH = 150
+
+// split reports whether the list is shown next to the playlist
+func (s mainModel) split() bool {
+	return s.width >= SPLIT_WIDTH
+}
+
+// listWidth returns the width of the list pane
+func (s mainModel) listWidth() int {
+	return s.width / 4
+}
+
+// resize gives the open model the size of its pane
+func (s *mainModel) resize() tea.Cmd {
+	if s.currentModel == nil {
+		return nil
+	}
+
+	msg := tea.WindowSizeMsg{Width: s.width, Height: s.height}
+	if playlistModel, ok := s.currentModel.(playlistModel); ok {
+		playlistModel.column = 0
+		if s.split() {
+			msg.Width = s.width - s.listWidth()
+			playlistModel.column = s.listWidth()
+		}
+		s.currentModel = playlistModel
+	}
+
+	model, cmd := s.currentModel.Update(msg)
+	s.currentModel = model
+	return cmd
+}
+
+// playlistModel returns the model that shows playlist
+func (s mainModel) playlistModel(playlist *data.Playlist) playlistModel {
+	playlistModel := newPlaylistModel(s.dr, s.width, s.height, playlist)
+	playlistModel.keys = s.keys
+	playlistModel.theme = s.theme
+	playlistModel.opener = opener.Resolve(s.opener, playlist)
+	playlistModel.player = s.player
+	playlistModel.thumbnails = s.thumbnails
+	playlistModel.thumbnailProtocol = s.thumbnailProtocol
+	playlistModel.output = s.output
+	playlistModel.lastViewed = playlist.LastViewed
+	playlistModel.newWindow = s.newWindow
+	playlistModel.refreshing = s.refreshing
+	return playlistModel
+}
+
+// preview renders the playlist at the cursor in the size of its pane
+func (s mainModel) preview() string {
+	width := s.width - s.listWidth()
+	if len(s.trackedPlaylists) == 0 {
+		return makeTobBarTitle("Playlist", width) +
+			makeLine(" no playlist tracked", width) +
+			makeBottomBar(width)
 	}
-	text += "\n"
 
-	for i, playlist := range s.trackedPlaylists {
+	preview := s.playlistModel(&s.trackedPlaylists[s.cursor])
+	preview.width = width
+	preview.itemsPerPage = pageSize(s.height)
+	return preview.View()
+}
+
+// listPane returns the short list of the tracked playlists shown next to
+// the playlist
+func (s mainModel) listPane() string {
+	width := s.listWidth()
+	keymaps := s.keymaps(width)
+
+	text := makeTobBarTitle(fmt.Sprintf("Playlists (%s)%s", s.listSortLabel(), s.refreshSummary()), width)
+	visible := max(s.height-strings.Count(keymaps, "\n")-3, 1)
+	window := GetWindow(s.cursor, visible)
+	now := time.Now()
+	for i := window.Start; i < window.End; i++ {
+		if i >= len(s.trackedPlaylists) {
+			text += makeLine("", width)
+			continue
+		}
+		playlist := &s.trackedPlaylists[i]
+
 		cursor := " "
 		if s.cursor == i {
 			cursor = ">"
 		}
+		newText := "   "
+		if count := playlist.NewCount(s.newWindow, now); count > 0 {
+			newText = s.theme.highlight + fmt.Sprintf("%3d", count) + RESET
+		}
+		progress := fmt.Sprintf(" %s %3.0f%%", newText, playlistProgress(playlist)*100)
 
-                updatedText := " "
-                if playlist.Updated {
-                        updatedText = "*"
-                }
-		descriptionPadding := maxLenTitle - len(playlist.Title) + 1
-		text += fmt.Sprintf(
-			"%s %s %s %s │ %s",
-			cursor,
-			playlist.Title,
-			strings.Repeat(" ", descriptionPadding),
-                        updatedText,
-			playlist.Description,
-		) + "\n"
+		title := playlist.Title
+		if s.refreshing.running(playlist.Id) {
+			title = s.refreshing.spinner() + " " + title
+		}
+		title = utility.Pad(title, width-3-2-utility.Width(progress))
+		text += makeLine(cursor+" "+title+progress, width)
 	}
+	text += makeBottomBar(width)
+
+	return text + keymaps
+}
 
-	for i := 0; i < s.width; i++ {
-		if i == maxLenTitle+7 {
-			text += "┴"
+// MAX_LEN_CHANNEL is the widest the channel column gets
+const MAX_LEN_CHANNEL = 20
+
+// PROGRESS_BAR_WIDTH is the number of cells of a progress bar
+const PROGRESS_BAR_WIDTH = 10
+
+// makeColumnBorder returns a line of width with cross at the column
+// separators of header
+func makeColumnBorder(header string, cross string, width int) string {
+	border := ""
+	runes := []rune(header)
+	for i := 0; i < width; i++ {
+		if i < len(runes) && string(runes[i]) == VERTICAL_BAR {
+			border += cross
 		} else {
-			text += "─"
+			border += HORIZONTAL_BAR
 		}
 	}
-	text += "\n"
+	return border + "\n"
+}
 
-	text += makeTobBarTitle("Keymaps", s.width)
-	text += makeLine(" * <q>     -> quit", s.width)
-	text += makeLine(" * <F5>    -> remove playlist", s.width)
-	text += makeLine(" * <s>     -> search playlist", s.width)
-	text += makeLine(" * <space> -> view playlist", s.width)
-	text += makeBottomBar(s.width)
+// progressColumn returns the watched count, a progress bar and the
+// percentage of watched videos, e.g. " 12/40  ███░░░░░░░  30%"
+func progressColumn(playlist *data.Playlist) string {
+	watched := playlist.WatchedCount()
+	total := playlist.Length()
+	progress := playlistProgress(playlist)
+	filled := int(progress * PROGRESS_BAR_WIDTH)
 
-	return text
+	return fmt.Sprintf("%4d/%-4d %s%s %3.0f%%", watched, total,
+		strings.Repeat("█", filled), strings.Repeat("░", PROGRESS_BAR_WIDTH-filled), progress*100)
+}
+
+// playlistProgress returns the share of watched videos from 0 to 1
+func playlistProgress(playlist *data.Playlist) float64 {
+	if playlist.Length() == 0 {
+		return 0
+	}
+	return float64(playlist.WatchedCount()) / float64(playlist.Length())
+}
+
+// formatRemaining returns the time it takes to watch the rest of the
+// playlist, "-" if there is nothing left
+func formatRemaining(playlist *data.Playlist) string {
+	remaining := playlist.Remaining()
+	if remaining == 0 {
+		if playlist.WatchedCount() == playlist.Length() {
+			return "-"
+		}
+		return "?"
+	}
+
+	hours := int(remaining.Hours())
+	minutes := int(remaining.Minutes()) % 60
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%02dm", hours, minutes)
+}
+
+// formatAgo returns how long ago t was, e.g. "5m ago"
+func formatAgo(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+}
+
+// runDownloads downloads the queued videos until the queue is empty
+func (s mainModel) runDownloads() tea.Cmd {
+	return func() tea.Msg {
+		return msgDownloadsDone{s.downloader.Run(context.Background())}
+	}
+}
+
+func (s mainModel) downloadTick() tea.Cmd {
+	return tea.Tick(DOWNLOAD_REFRESH, func(time.Time) tea.Msg {
+		return msgDownloadTick{}
+	})
+}
+
+// reloadPlaylists replaces every tracked playlist with the stored one, like
+// reloadPlaylist. A failure is returned as a command that shows it.
+func (s *mainModel) reloadPlaylists() tea.Cmd {
+	playlists, err := s.dr.GetPlaylists()
+	if err != nil {
+		return reportError("could not reload the playlists", err)
+	}
+
+	for idx := range playlists {
+		playlists[idx].Sort()
+		for tidx := range s.trackedPlaylists {
+			if s.trackedPlaylists[tidx].Id == playlists[idx].Id {
+				s.trackedPlaylists[tidx] = playlists[idx]
+			}
+		}
+	}
+	return nil
+}
+
+// reloadPlaylist replaces the playlist with the given id with the stored
+// one. The playlist model shows the same playlist, so it sees the change.
+func (s *mainModel) reloadPlaylist(id string) tea.Cmd {
+	playlist, err := data.GetPlaylist(s.dr, id)
+	if err != nil {
+		return reportError("could not reload the playlist", err)
+	}
+	playlist.Sort()
+
+	for idx := range s.trackedPlaylists {
+		if s.trackedPlaylists[idx].Id == id {
+			s.trackedPlaylists[idx] = *playlist
+		}
+	}
+	return nil
 }
 
 // isTracked returns whether the given playlist (id) is already in the list
@@ -231,13 +751,108 @@ func (s *mainModel) isTracked(playlistId string) bool {
 
 // addPlaylist checks if the given playlist is not in the list
 // and then adds it. It will also update cursor position.
-func (s *mainModel) addPlaylist(playlist data.Playlist) {
-	if !s.isTracked(playlist.Id) {
-		err := s.dr.SavePlaylist(&playlist)
-		if err != nil {
-			log.Fatal(err)
+// If it can not be saved the returned command shows the error.
+func (s *mainModel) addPlaylist(playlist data.Playlist) tea.Cmd {
+	if s.isTracked(playlist.Id) {
+		return nil
+	}
+
+	// the playlist was just fetched, like with tubevault add
+	playlist.LastRefreshed = time.Now()
+	err := s.dr.SavePlaylist(&playlist)
+	if err != nil {
+		return func() tea.Msg {
+			return errorStatus("could not save the playlist", err, func() tea.Msg {
+				return msgSearchedResult{playlist}
+			})
+		}
+	}
+	playlist.Sort()
+	s.trackedPlaylists = append(s.trackedPlaylists, playlist)
+	s.cursor = len(s.trackedPlaylists) - 1
+	s.sortPlaylists()
+	return nil
+}
+
+// orders of the tracked playlists, an empty order keeps the order of the
+// vault
+const (
+	LIST_SORT_TITLE     = "title"
+	LIST_SORT_PROGRESS  = "progress"
+	LIST_SORT_REMAINING = "remaining"
+	LIST_SORT_REFRESHED = "refreshed"
+	LIST_SORT_CHANNEL   = "channel"
+	LIST_SORT_NEW       = "new"
+)
+
+var LIST_SORT_MODES = []string{LIST_SORT_TITLE, LIST_SORT_PROGRESS, LIST_SORT_REMAINING,
+	LIST_SORT_REFRESHED, LIST_SORT_CHANNEL, LIST_SORT_NEW}
+
+// listSortLabel describes the order of the tracked playlists
+func (s mainModel) listSortLabel() string {
+	if s.listSort == "" {
+		return "vault order"
+	}
+	if s.listSortDescending {
+		return s.listSort + ", descending"
+	}
+	return s.listSort + ", ascending"
+}
+
+// sortPlaylists sorts the tracked playlists by the chosen order and keeps
+// the cursor on the same playlist. The open playlist model points into the
+// list, so it is sorted once the playlist is closed.
+func (s *mainModel) sortPlaylists() {
+	if s.listSort == "" || len(s.trackedPlaylists) == 0 {
+		return
+	}
+	if _, ok := s.currentModel.(playlistModel); ok {
+		return
+	}
+	selected := s.trackedPlaylists[min(s.cursor, len(s.trackedPlaylists)-1)].Id
+
+	compare := comparePlaylists(s.listSort, s.newWindow)
+	sort.SliceStable(s.trackedPlaylists, func(i, j int) bool {
+		if s.listSortDescending {
+			return compare(&s.trackedPlaylists[j], &s.trackedPlaylists[i]) < 0
+		}
+		return compare(&s.trackedPlaylists[i], &s.trackedPlaylists[j]) < 0
+	})
+
+	for idx := range s.trackedPlaylists {
+		if s.trackedPlaylists[idx].Id == selected {
+			s.cursor = idx
 		}
-		s.trackedPlaylists = append(s.trackedPlaylists, playlist)
-		s.cursor = len(s.trackedPlaylists) - 1
+	}
+}
+
+// comparePlaylists returns the comparison of the list sort mode, videos
+// are new for newWindow
+func comparePlaylists(mode string, newWindow time.Duration) func(a *data.Playlist, b *data.Playlist) int {
+	switch mode {
+	case LIST_SORT_PROGRESS:
+		return func(a *data.Playlist, b *data.Playlist) int {
+			return cmp.Compare(playlistProgress(a), playlistProgress(b))
+		}
+	case LIST_SORT_REMAINING:
+		return func(a *data.Playlist, b *data.Playlist) int {
+			return cmp.Compare(a.Remaining(), b.Remaining())
+		}
+	case LIST_SORT_REFRESHED:
+		return func(a *data.Playlist, b *data.Playlist) int {
+			return a.LastRefreshed.Compare(b.LastRefreshed)
+		}
+	case LIST_SORT_CHANNEL:
+		return func(a *data.Playlist, b *data.Playlist) int {
+			return strings.Compare(strings.ToLower(a.ChannelName()), strings.ToLower(b.ChannelName()))
+		}
+	case LIST_SORT_NEW:
+		now := time.Now()
+		return func(a *data.Playlist, b *data.Playlist) int {
+			return cmp.Compare(a.NewCount(newWindow, now), b.NewCount(newWindow, now))
+		}
+	}
+	return func(a *data.Playlist, b *data.Playlist) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
 	}
 }
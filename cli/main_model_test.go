@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/utility"
+)
+
+type ListWidthTest struct {
+	title string
+	width int
+}
+
+var listWidthTests = []ListWidthTest{
+	{"Short", 120},
+	{strings.Repeat("Very long title ", 20), 120},
+	{strings.Repeat("Very long title ", 20), 100},
+}
+
+func TestListWidth(t *testing.T) {
+	keys, err := newKeymap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range listWidthTests {
+		s := initialModel()
+		s.keys = keys
+		s.width = test.width
+		s.height = 40
+		s.trackedPlaylists = []data.Playlist{{Id: "PL1", Title: test.title}}
+
+		for _, line := range strings.Split(s.View(), "\n") {
+			if utility.Width(line) > test.width {
+				t.Fatalf("Wanted lines at most %d wide, got %d: %q", test.width, utility.Width(line), line)
+			}
+		}
+	}
+}
+
+func TestAddPlaylistRefreshed(t *testing.T) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := initialModel()
+	s.dr = dr
+	s.addPlaylist(data.Playlist{Id: "PL1", Title: "Playlist"})
+
+	playlist, err := data.GetPlaylist(dr, "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playlist.LastRefreshed.IsZero() {
+		t.Fatal("Wanted LastRefreshed to be set for an added playlist")
+	}
+}
@@ -2,22 +2,29 @@ package cli
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"strings"
 	"time"
 
 	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/mpv"
+	"github.com/baumple/watchvault/opener"
+	"github.com/baumple/watchvault/thumbnail"
 	"github.com/baumple/watchvault/utility"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 const (
-	SECONDS_DAY = 86400
+	// DETAIL_SPLIT_WIDTH is the narrowest playlist view that shows the
+	// video details next to the videos
+	DETAIL_SPLIT_WIDTH = 100
 )
 
 type playlistModel struct {
 	width  int
 	height int
+	// column is the first screen column of the view
+	column int
 
 	itemsPerPage int
 
@@ -27,8 +34,36 @@ type playlistModel struct {
 	visualMode  bool
 	visualStart int
 
+	// filter narrows the list to the videos that match it, filtering is
+	// set while it is typed
+	filter    string
+	filtering bool
+
+	// the view filters hide videos, they are combined
+	hideWatched bool
+	onlyNew     bool
+	inProgress  bool
+	withNotes   bool
+	// lastViewed is when the playlist was opened before, videos found
+	// after it and within newWindow are new
+	lastViewed time.Time
+	newWindow  time.Duration
+
 	dr data.DataRetriever
 
+	keys   keymap
+	theme  theme
+	opener string
+	player *mpv.Player
+
+	thumbnails        *thumbnail.Cache
+	thumbnailProtocol string
+	// output is the output of the program, see videoModel.output
+	output io.Writer
+
+	// refreshing holds the progress of the running refreshes
+	refreshing *refreshState
+
 	currentModel tea.Model
 }
 
@@ -37,6 +72,32 @@ func (p playlistModel) Init() tea.Cmd {
 }
 
 func (p playlistModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		p.height = msg.Height
+		p.width = msg.Width
+		p.itemsPerPage = pageSize(p.height)
+		return p, p.resize()
+	}
+
+	if msg, ok := msg.(msgRefreshProgress); ok {
+		if msg.done && msg.err == nil && msg.playlistId == p.playlist.Id {
+			p.keepSelection(p.rows(), func() {
+				*p.playlist = *msg.playlist
+				p.playlist.Sort()
+			})
+
+			// the open details show the video in the new list, otherwise
+			// their changes would not show up in it
+			if videoModel, ok := p.currentModel.(videoModel); ok {
+				if video := p.playlist.FindVideo(videoModel.video.Id); video != nil {
+					videoModel.video = video
+					p.currentModel = videoModel
+				}
+			}
+		}
+		return p, nil
+	}
+
 	if p.currentModel != nil {
 		model, cmd := p.currentModel.Update(msg)
 		p.currentModel = model
@@ -44,72 +105,163 @@ func (p playlistModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	switch msg := msg.(type) {
-	case tea.WindowSizeMsg:
-		p.height = msg.Height
-		p.width = msg.Width
-		p.itemsPerPage = p.height / 3
-
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "enter":
-			if p.playlist.Length() > 0 {
-				p.currentModel = newVideoModel(&p.playlist.Videos[p.cursor], p.width, p.height)
+		if p.filtering {
+			return p.handleFilterInput(msg)
+		}
+
+		rows := p.rows()
+		p.cursor = max(min(p.cursor, len(rows)-1), 0)
+
+		switch key := msg.String(); {
+		case p.keys.is(key, ACTION_DETAILS):
+			if len(rows) > 0 {
+				videoModel := newVideoModel(&p.playlist.Videos[rows[p.cursor]], p.width, p.height)
+				videoModel.dr = p.dr
+				videoModel.keys = p.keys
+				videoModel.theme = p.theme
+				videoModel.opener = p.opener
+				videoModel.thumbnails = p.thumbnails
+				videoModel.thumbnailProtocol = p.thumbnailProtocol
+				videoModel.output = p.output
+				p.currentModel = videoModel
+				return p, tea.Batch(videoModel.Init(), p.resize())
+			}
+
+		case p.keys.is(key, ACTION_OPEN_VIDEO):
+			if len(rows) > 0 {
+				video := &p.playlist.Videos[rows[p.cursor]]
+				return p, open(p.opener, opener.ForVideo(video))
+			}
+
+		case p.keys.is(key, ACTION_PLAY):
+			if len(rows) > 0 {
+				return p, play(p.player, p.playlist.Id, []data.Video{p.playlist.Videos[rows[p.cursor]]})
+			}
+
+		case p.keys.is(key, ACTION_QUEUE):
+			if len(rows) > 0 {
+				return p, play(p.player, p.playlist.Id, mpv.Queue(p.playlist.Videos, rows[p.cursor]))
 			}
 
-		case "q", "ctrl+c":
+		case p.keys.is(key, ACTION_FILTER):
+			p.visualMode = false
+			p.filtering = true
+
+		case p.keys.is(key, ACTION_REFRESH):
+			id := p.playlist.Id
+			return p, func() tea.Msg {
+				return msgRefresh{[]string{id}}
+			}
+
+		case p.keys.is(key, ACTION_HIDE_WATCHED):
+			p.toggleViewFilter(&p.hideWatched)
+		case p.keys.is(key, ACTION_ONLY_NEW):
+			p.toggleViewFilter(&p.onlyNew)
+		case p.keys.is(key, ACTION_IN_PROGRESS):
+			p.toggleViewFilter(&p.inProgress)
+		case p.keys.is(key, ACTION_WITH_NOTES):
+			p.toggleViewFilter(&p.withNotes)
+
+		case p.keys.is(key, ACTION_SORT), p.keys.is(key, ACTION_REVERSE):
+			if p.keys.is(key, ACTION_SORT) {
+				p.playlist.SortMode = data.NextSortMode(p.playlist.SortMode)
+			} else {
+				p.playlist.SortDescending = !p.playlist.SortDescending
+			}
+			p.visualMode = false
+			p.resort(rows)
+
+			// the order is remembered for the playlist
+			dr := p.dr
+			id, mode, descending := p.playlist.Id, p.playlist.SortMode, p.playlist.SortDescending
+			return p, write("could not save the order", func() error {
+				return dr.UpdatePlaylistSort(id, mode, descending)
+			})
+
+		case p.keys.is(key, ACTION_DOWNLOAD):
+			if len(rows) <= 0 {
+				break
+			}
+			p.visualMode = false
+			selection := p.getSelectionIndices()
+			p.visualStart = p.cursor
+
+			// queued videos are removed from the queue again
+			ids := []string{}
+			allQueued := true
+			for i := selection.Start; i < selection.End; i++ {
+				video := &p.playlist.Videos[rows[i]]
+				ids = append(ids, video.Id)
+				allQueued = allQueued && video.Download.Status == data.DOWNLOAD_QUEUED
+			}
+			if allQueued {
+				return p, cancelDownloads(p.dr, p.playlist.Id, ids...)
+			}
+			return p, queueDownloads(p.dr, p.playlist.Id, ids...)
+
+		case p.keys.is(key, ACTION_QUIT):
 			return p, tea.Quit
 
-		case "esc":
-			if p.visualMode {
+		case p.keys.is(key, ACTION_BACK):
+			switch {
+			case p.visualMode:
 				p.visualMode = false
-			} else {
+			case p.filter != "":
+				p.setFilter("")
+			default:
 				return nil, nil
 			}
 
-		case "down", "j":
-			if p.cursor < len(p.playlist.Videos)-1 {
+		case p.keys.is(key, ACTION_DOWN):
+			if p.cursor < len(rows)-1 {
 				p.cursor++
 			}
 
-		case "up", "k":
+		case p.keys.is(key, ACTION_UP):
 			if p.cursor > 0 {
 				p.cursor--
 			}
-		case "v":
+		case p.keys.is(key, ACTION_VISUAL):
 			p.visualMode = !p.visualMode
 
-		case "ctrl+u":
+		case p.keys.is(key, ACTION_PAGE_UP):
 			p.cursor = max(p.cursor-15, 0)
 
-		case "ctrl+d":
-			p.cursor = min(p.cursor+15, len(p.playlist.Videos)-1)
+		case p.keys.is(key, ACTION_PAGE_DOWN):
+			p.cursor = max(min(p.cursor+15, len(rows)-1), 0)
 
-		case "G":
-			p.cursor = p.playlist.Length() - 1
-		case "g":
+		case p.keys.is(key, ACTION_BOTTOM):
+			p.cursor = max(len(rows)-1, 0)
+		case p.keys.is(key, ACTION_TOP):
 			p.cursor = 0
 
-		case " ":
+		case p.keys.is(key, ACTION_TOGGLE_WATCHED):
+			if len(rows) <= 0 {
+				break
+			}
 			if p.visualMode {
 				p.visualMode = false
 			}
 			selection := p.getSelectionIndices()
 			p.visualStart = p.cursor
 
-			for i := selection.start; i < selection.end; i++ {
-				video := &p.playlist.Videos[i]
-				video.Watched = !video.Watched
+			toggled := []*data.Video{}
+			for i := selection.Start; i < selection.End; i++ {
+				video := &p.playlist.Videos[rows[i]]
+				video.SetWatched(!video.Watched)
+				toggled = append(toggled, video)
 			}
 
-			return p, func() tea.Msg {
-				for i := selection.start; i < selection.end; i++ {
-					err := p.dr.UpdateVideoWatched(p.playlist.Id, p.playlist.Videos[i].Id, p.playlist.Videos[i].Watched)
+			return p, write("could not save the watched state", func() error {
+				for _, video := range toggled {
+					err := p.dr.UpdateVideoWatched(p.playlist.Id, video.Id, video.Watched)
 					if err != nil {
-						log.Fatal(err)
+						return err
 					}
 				}
 				return nil
-			}
+			})
 		}
 	}
 
@@ -119,12 +271,226 @@ func (p playlistModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return p, nil
 }
 
+// handleFilterInput edits the filter while it is typed
+func (p playlistModel) handleFilterInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return p, tea.Quit
+	case tea.KeyEnter:
+		p.filtering = false
+	case tea.KeyEsc:
+		p.filtering = false
+		p.setFilter("")
+	case tea.KeyBackspace:
+		runes := []rune(p.filter)
+		if len(runes) > 0 {
+			p.setFilter(string(runes[:len(runes)-1]))
+		}
+	case tea.KeySpace:
+		p.setFilter(p.filter + " ")
+	case tea.KeyRunes:
+		p.setFilter(p.filter + string(msg.Runes))
+	}
+	return p, nil
+}
+
+// setFilter changes the filter and keeps the cursor on the same video if
+// it still matches
+func (p *playlistModel) setFilter(filter string) {
+	p.changeRows(func() {
+		p.filter = filter
+	})
+}
+
+// toggleViewFilter turns a view filter on or off
+func (p *playlistModel) toggleViewFilter(filter *bool) {
+	p.visualMode = false
+	p.changeRows(func() {
+		*filter = !*filter
+	})
+}
+
+// changeRows applies change to the listed videos and keeps the cursor on
+// the same video if it is still listed
+func (p *playlistModel) changeRows(change func()) {
+	selected := -1
+	if rows := p.rows(); p.cursor < len(rows) {
+		selected = rows[p.cursor]
+	}
+
+	change()
+	p.cursor = 0
+	for row, idx := range p.rows() {
+		if idx == selected {
+			p.cursor = row
+		}
+	}
+	p.visualStart = p.cursor
+}
+
+// resort sorts the videos in the order of the playlist and keeps the
+// cursor on the same video
+func (p *playlistModel) resort(rows []int) {
+	p.keepSelection(rows, p.playlist.Sort)
+}
+
+// keepSelection applies change to the videos and moves the cursor back to
+// the video it was on. rows are the listed videos before the change.
+func (p *playlistModel) keepSelection(rows []int, change func()) {
+	selected := ""
+	if p.cursor < len(rows) {
+		selected = p.playlist.Videos[rows[p.cursor]].Id
+	}
+
+	change()
+
+	p.cursor = 0
+	for row, idx := range p.rows() {
+		if p.playlist.Videos[idx].Id == selected {
+			p.cursor = row
+		}
+	}
+	p.visualStart = p.cursor
+}
+
+// rows returns the indices of the videos that are listed
+func (p playlistModel) rows() []int {
+	rows := []int{}
+	for idx := range p.playlist.Videos {
+		video := &p.playlist.Videos[idx]
+		if _, ok := matchVideo(video, p.filter); ok && p.shown(video) {
+			rows = append(rows, idx)
+		}
+	}
+	return rows
+}
+
+// shown reports whether the video passes the view filters
+func (p playlistModel) shown(video *data.Video) bool {
+	switch {
+	case p.hideWatched && video.Watched:
+		return false
+	case p.onlyNew && !video.IsNew(p.lastViewed, p.newWindow, time.Now()):
+		return false
+	case p.inProgress && !video.InProgress():
+		return false
+	case p.withNotes && strings.TrimSpace(video.Notes) == "":
+		return false
+	}
+	return true
+}
+
+// viewFilters returns the names of the active view filters
+func (p playlistModel) viewFilters() []string {
+	filters := []string{}
+	if p.hideWatched {
+		filters = append(filters, "unwatched")
+	}
+	if p.onlyNew {
+		filters = append(filters, "new")
+	}
+	if p.inProgress {
+		filters = append(filters, "in progress")
+	}
+	if p.withNotes {
+		filters = append(filters, "with notes")
+	}
+	return filters
+}
+
+// matchVideo fuzzy matches the filter against the title of the video and
+// returns the matched runes of the title. The description has to contain
+// the filter, a fuzzy match would hit almost every long description.
+func matchVideo(video *data.Video, filter string) ([]int, bool) {
+	if filter == "" {
+		return nil, true
+	}
+	if matches := utility.FuzzyMatch(filter, video.Title); matches != nil {
+		return matches, true
+	}
+	return nil, strings.Contains(strings.ToLower(video.Description), strings.ToLower(filter))
+}
+
+// highlight marks the runes of text at the given indices
+func highlight(text string, indices []int, on string, off string) string {
+	if len(indices) == 0 {
+		return text
+	}
+
+	res := strings.Builder{}
+	next := 0
+	for idx, r := range []rune(text) {
+		if next < len(indices) && indices[next] == idx {
+			res.WriteString(on + string(r) + off)
+			next++
+			continue
+		}
+		res.WriteRune(r)
+	}
+	return res.String()
+}
+
+// split reports whether the video details are shown next to the videos
+func (p playlistModel) split() bool {
+	return p.width >= DETAIL_SPLIT_WIDTH
+}
+
+// videosWidth returns the width of the videos pane
+func (p playlistModel) videosWidth() int {
+	if p.split() {
+		return p.width / 2
+	}
+	return p.width
+}
+
+// resize gives the video details the size of their pane
+func (p *playlistModel) resize() tea.Cmd {
+	width, column := p.width, p.column
+	if p.split() {
+		width = p.width - p.videosWidth()
+		column += p.videosWidth()
+	}
+	model, cmd := resizeDetails(p.currentModel, width, p.height, column)
+	p.currentModel = model
+	return cmd
+}
+
+// preview returns the details of the video at the cursor
+func (p playlistModel) preview(width int) string {
+	rows := p.rows()
+	if len(rows) == 0 {
+		return previewVideo(nil, width, p.height, p.keys, p.theme)
+	}
+	video := &p.playlist.Videos[rows[max(min(p.cursor, len(rows)-1), 0)]]
+	return previewVideo(video, width, p.height, p.keys, p.theme)
+}
+
 func (p playlistModel) View() string {
+	if !p.split() {
+		if p.currentModel != nil {
+			return p.currentModel.View()
+		}
+		return p.view()
+	}
+
+	detailWidth := p.width - p.videosWidth()
+	detail := p.preview(detailWidth)
 	if p.currentModel != nil {
-		return p.currentModel.View()
+		detail = p.currentModel.View()
 	}
 
-	text := makeTobBarTitle("Playlist", p.width)
+	videos := p
+	videos.width = p.videosWidth()
+	return joinPanes(p.height, pane{videos.view(), videos.width}, pane{detail, detailWidth})
+}
+
+// view renders the playlist and its videos
+func (p playlistModel) view() string {
+	title := "Playlist"
+	if p.refreshing.running(p.playlist.Id) {
+		title = fmt.Sprintf("Playlist %s refreshing, %s", p.refreshing.spinner(), p.refreshing.label(p.playlist.Id))
+	}
+	text := makeTobBarTitle(title, p.width)
 	text += makeLine(" "+p.playlist.Title, p.width)
 	text += makeSeparatorTitle("Published at", p.width)
 	text += makeLine(" "+p.playlist.PublishedAt.String(), p.width)
@@ -134,29 +500,47 @@ func (p playlistModel) View() string {
 		text += makeLine(" ...", p.width)
 	}
 
-	lines := utility.SplitEveryN(p.playlist.Description, p.width)
+	lines := utility.WordWrap(p.playlist.Description, p.width-4)
 
 	for _, line := range lines {
 		text += makeLine(" "+line, p.width)
 	}
 
-	text += makeSeparator(p.width)
+	text += makeSeparatorTitle("Videos ("+p.playlist.SortLabel()+")", p.width)
+
+	rows := p.rows()
+	viewFilters := p.viewFilters()
+	if p.filtering || p.filter != "" {
+		filterCursor := ""
+		if p.filtering {
+			filterCursor = CURSOR
+		}
+		text += makeLine(fmt.Sprintf(" /%s%s", p.filter, filterCursor), p.width)
+	}
+	if len(viewFilters) > 0 {
+		text += makeLine(" only "+strings.Join(viewFilters, ", "), p.width)
+	}
+	if p.filtering || p.filter != "" || len(viewFilters) > 0 {
+		text += makeLine(fmt.Sprintf(" %d of %d videos shown, %d hidden",
+			len(rows), p.playlist.Length(), p.playlist.Length()-len(rows)), p.width)
+		text += makeSeparator(p.width)
+	}
 
-	nVideos := len(p.playlist.Videos)
+	nVideos := len(rows)
 	selection := p.getSelectionIndices()
 
-	currentTime := time.Now().Unix()
+	now := time.Now()
 	windowIndices := GetWindow(p.cursor, p.itemsPerPage)
 
 	// ratio between current window and total elements
-	startProgress := float32(windowIndices.Start) / float32(p.playlist.Length())
+	startProgress := float32(windowIndices.Start) / float32(nVideos)
 
 	// take that ratio and multiply it with height of item list
 	// -> relative start index
 	barStart := windowIndices.Start + int(float32(p.height/2)*startProgress)
 
 	// same for progressbar
-	endProgress := float32(windowIndices.End) / float32(p.playlist.Length())
+	endProgress := float32(windowIndices.End) / float32(nVideos)
 	barEnd := windowIndices.Start + int(float32(p.height/2)*endProgress)
 
 	for i := windowIndices.Start; i < windowIndices.End; i++ {
@@ -167,7 +551,7 @@ func (p playlistModel) View() string {
 		leftBar := VERTICAL_BAR
 
 		if i >= barStart && i <= barEnd {
-			leftBar = "\033[34m" + ALT_VERTICAL_BAR + "\033[0m"
+			leftBar = p.theme.accent + ALT_VERTICAL_BAR + RESET
 		}
 
 		if i >= nVideos {
@@ -175,7 +559,7 @@ func (p playlistModel) View() string {
 			continue
 		}
 
-		video := &p.playlist.Videos[i]
+		video := &p.playlist.Videos[rows[i]]
 
 		cursor := " "
 		if p.cursor == i {
@@ -186,46 +570,51 @@ func (p playlistModel) View() string {
 		if video.Watched {
 			watched = "[X]"
 		}
+		downloadStatus := getDownloadStatus(video)
 
 		modifier := ""
-		if p.visualMode && i >= selection.start && i < selection.end {
-			modifier = "\033[;5m"
+		if p.visualMode && i >= selection.Start && i < selection.End {
+			modifier = p.theme.selection
 		}
 
-		// if the video is newer than three days mark it as "NEW"
+		// videos found since the playlist was viewed the last time
 		newText := "     "
-		if currentTime-video.PublishedAt.Unix() < SECONDS_DAY*3 {
-			newText = ">NEW<"
+		if video.IsNew(p.lastViewed, p.newWindow, now) {
+			newText = p.theme.highlight + ">NEW<" + RESET + modifier
 		}
 
-                text += leftBar
-		text += fmt.Sprintf(
-			"%s %s %s %s %s\033[0m",
+		matches, _ := matchVideo(video, p.filter)
+		title := highlight(video.Title, matches, p.theme.highlight, RESET+modifier)
+
+		row := fmt.Sprintf(
+			"%s %s %s %s %s %s\033[0m",
 			modifier,
 			cursor,
 			watched,
+			downloadStatus,
 			newText,
-			video.Title,
+			title,
 		)
-
-		whiteSpaceLeft := p.width -
-			len(video.Title) -
-			1 - // cursor
-			3 - // watched
-			2 // borders
-
-		text += strings.Repeat(" ", whiteSpaceLeft)
-		text += VERTICAL_BAR
-		text += "\n"
-
+		text += makeLineBorders(row, leftBar, VERTICAL_BAR, p.width)
 	}
 
 	text += makeBottomBar(p.width)
 
 	text += makeTobBarTitle("Keymaps", p.width)
-	text += makeLine("  * <esc>  -> return", p.width)
-	text += makeLine("  * <space> -> toggle watched", p.width)
-	text += makeLine("  * <v>     -> visual mode", p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> return", p.keys.help(ACTION_BACK)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> toggle watched", p.keys.help(ACTION_TOGGLE_WATCHED)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> visual mode", p.keys.help(ACTION_VISUAL)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> refresh playlist", p.keys.help(ACTION_REFRESH)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> filter videos", p.keys.help(ACTION_FILTER)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> change/reverse the order", p.keys.help(ACTION_SORT)+"/"+p.keys.help(ACTION_REVERSE)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> hide watched videos", p.keys.help(ACTION_HIDE_WATCHED)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> only new/in progress/with notes",
+		p.keys.help(ACTION_ONLY_NEW)+p.keys.help(ACTION_IN_PROGRESS)+p.keys.help(ACTION_WITH_NOTES)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> video details", p.keys.help(ACTION_DETAILS)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> open video", p.keys.help(ACTION_OPEN_VIDEO)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> play in mpv", p.keys.help(ACTION_PLAY)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> play unwatched from here", p.keys.help(ACTION_QUEUE)), p.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> download for offline use", p.keys.help(ACTION_DOWNLOAD)), p.width)
 	text += makeBottomBar(p.width)
 
 	return text
@@ -233,11 +622,16 @@ func (p playlistModel) View() string {
 
 func NewPlaylistModel(dr data.DataRetriever, width int, height int, playlist *data.Playlist) playlistModel {
 	playlist.Sort()
+	return newPlaylistModel(dr, width, height, playlist)
+}
 
+// newPlaylistModel is NewPlaylistModel for a playlist that is sorted
+// already
+func newPlaylistModel(dr data.DataRetriever, width int, height int, playlist *data.Playlist) playlistModel {
 	return playlistModel{
 		width:        width,
 		height:       height,
-		itemsPerPage: height / 2,
+		itemsPerPage: pageSize(height),
 		cursor:       0,
 		playlist:     playlist,
 		visualMode:   false,
@@ -246,44 +640,53 @@ func NewPlaylistModel(dr data.DataRetriever, width int, height int, playlist *da
 	}
 }
 
-// TODO: REmove duplicate im too lazy rn
-func (p *playlistModel) getSelectionIndices() struct {
-	start int
-	end   int
-} {
-	selectionStart := p.visualStart
-	selectionEnd := p.cursor
+// pageSize returns how many videos are listed in a playlist view that is
+// height lines high
+func pageSize(height int) int {
+	return height / 3
+}
 
-	if selectionStart > selectionEnd {
-		temp := selectionEnd
-		selectionEnd = selectionStart
-		selectionStart = temp
+// getDownloadStatus returns the four characters wide download column
+func getDownloadStatus(video *data.Video) string {
+	switch video.Download.Status {
+	case data.DOWNLOAD_QUEUED:
+		return "wait"
+	case data.DOWNLOAD_DOWNLOADING:
+		return fmt.Sprintf("%3.0f%%", video.Download.Progress)
+	case data.DOWNLOAD_DONE:
+		return "disk"
+	case data.DOWNLOAD_FAILED:
+		return "fail"
 	}
+	return "    "
+}
 
-	return struct {
-		start int
-		end   int
-	}{
-		selectionStart,
-		selectionEnd + 1,
+// getSelectionIndices returns the rows selected in visual mode, only the
+// cursor outside of it
+func (p *playlistModel) getSelectionIndices() Rows {
+	return Rows{
+		Start: min(p.visualStart, p.cursor),
+		End:   max(p.visualStart, p.cursor) + 1,
 	}
 }
 
-func GetWindow(cursor int, width int) struct {
+// Rows are the rows from Start up to but not including End
+type Rows struct {
 	Start int
 	End   int
-} {
+}
+
+// GetWindow returns the rows shown around cursor, width rows starting at
+// the first one until the cursor passes the middle
+func GetWindow(cursor int, width int) Rows {
 	start := 0
 	end := 0
 	if cursor >= width/2 {
 		start = cursor - width/2
 		end = cursor + width/2
 	} else {
-		end += width - 1
+		end = width
 	}
 
-	return struct {
-		Start int
-		End   int
-	}{start, end}
+	return Rows{start, end}
 }
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/notify"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SPINNER_FRAMES are shown one after another while playlists are refreshed
+var SPINNER_FRAMES = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// SPINNER_INTERVAL is how long a frame of the spinner is shown
+const SPINNER_INTERVAL = 100 * time.Millisecond
+
+// msgRefresh asks to refresh the playlists with the given ids
+type msgRefresh struct {
+	ids []string
+}
+
+// msgRefreshProgress is sent by a running refresh after every fetched page
+// and once a playlist is done
+type msgRefreshProgress struct {
+	playlistId string
+	page       int
+	pages      int
+
+	// done is set when the playlist was refreshed, playlist is the stored
+	// playlist then
+	done     bool
+	playlist *data.Playlist
+	err      error
+
+	events <-chan msgRefreshProgress
+}
+
+// msgSpinnerTick shows the next frame of the spinner
+type msgSpinnerTick struct{}
+
+// refreshProgress is the fetched and the expected pages of a playlist,
+// no pages means it waits for its turn
+type refreshProgress struct {
+	page  int
+	pages int
+}
+
+// refreshState is shared by the models to show the running refreshes
+type refreshState struct {
+	// playlists are the playlists that are refreshed by id
+	playlists map[string]refreshProgress
+	frame     int
+	spinning  bool
+}
+
+func newRefreshState() *refreshState {
+	return &refreshState{playlists: map[string]refreshProgress{}}
+}
+
+// running reports whether the playlist with the given id is refreshed
+func (r *refreshState) running(id string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.playlists[id]
+	return ok
+}
+
+// count returns the number of playlists that are refreshed
+func (r *refreshState) count() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.playlists)
+}
+
+// spinner returns the current frame of the spinner
+func (r *refreshState) spinner() string {
+	return SPINNER_FRAMES[r.frame%len(SPINNER_FRAMES)]
+}
+
+// label describes the progress of the playlist with the given id, e.g.
+// "page 3/12"
+func (r *refreshState) label(id string) string {
+	if !r.running(id) {
+		return ""
+	}
+
+	progress := r.playlists[id]
+	if progress.pages == 0 {
+		return "queued"
+	}
+	return fmt.Sprintf("page %d/%d", progress.page, progress.pages)
+}
+
+// refreshPlaylists refreshes the playlists one after another and reports
+// the progress as msgRefreshProgress
+func refreshPlaylists(dr data.DataRetriever, yt *data.YouTubeApi, notifier *notify.Notifier, ids []string) tea.Cmd {
+	return func() tea.Msg {
+		events := make(chan msgRefreshProgress)
+		go func() {
+			defer close(events)
+			for _, id := range ids {
+				playlist, newVideos, err := data.RefreshPlaylistProgress(dr, yt, id, func(page int, pages int) {
+					events <- msgRefreshProgress{playlistId: id, page: page, pages: pages}
+				})
+				if err == nil {
+					// a failed notification must not stop the interface
+					notifier.Notify(playlist, newVideos)
+				}
+				events <- msgRefreshProgress{playlistId: id, done: true, playlist: playlist, err: err}
+			}
+		}()
+		return waitRefresh(events)()
+	}
+}
+
+// waitRefresh waits for the next message of a running refresh
+func waitRefresh(events <-chan msgRefreshProgress) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		msg.events = events
+		return msg
+	}
+}
+
+// refresh starts refreshing the playlists with the given ids that are not
+// refreshed already
+func (s *mainModel) refresh(ids []string) tea.Cmd {
+	queued := []string{}
+	for _, id := range ids {
+		if !s.refreshing.running(id) {
+			s.refreshing.playlists[id] = refreshProgress{}
+			queued = append(queued, id)
+		}
+	}
+	if len(queued) == 0 {
+		return nil
+	}
+
+	cmd := refreshPlaylists(s.dr, &s.yt, s.notifier, queued)
+	if s.refreshing.spinning {
+		return cmd
+	}
+	s.refreshing.spinning = true
+	return tea.Batch(cmd, spinnerTick())
+}
+
+// refreshDue asks to refresh every tracked playlist that was not
+// refreshed within its refresh interval (e.g. by the daemon)
+func (s mainModel) refreshDue() tea.Cmd {
+	return func() tea.Msg {
+		playlists, err := s.dr.GetPlaylists()
+		if err != nil {
+			return errorStatus("could not refresh the playlists", err, s.refreshDue())
+		}
+
+		ids := []string{}
+		for idx := range playlists {
+			if playlists[idx].RefreshDue(s.refreshInterval, time.Now()) {
+				ids = append(ids, playlists[idx].Id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return msgRefresh{ids}
+	}
+}
+
+// refreshed stores the progress of a refresh. Refreshed playlists replace
+// the tracked ones, the cursor stays on the same playlist.
+func (s *mainModel) refreshed(msg msgRefreshProgress) tea.Cmd {
+	wait := waitRefresh(msg.events)
+	if !msg.done {
+		s.refreshing.playlists[msg.playlistId] = refreshProgress{msg.page, msg.pages}
+		return wait
+	}
+
+	delete(s.refreshing.playlists, msg.playlistId)
+	if msg.err != nil {
+		title := msg.playlistId
+		for idx := range s.trackedPlaylists {
+			if s.trackedPlaylists[idx].Id == msg.playlistId {
+				title = s.trackedPlaylists[idx].Title
+			}
+		}
+		retry := func() tea.Msg {
+			return msgRefresh{[]string{msg.playlistId}}
+		}
+		return tea.Batch(wait, func() tea.Msg {
+			return errorStatus("could not refresh "+title, msg.err, retry)
+		})
+	}
+
+	msg.playlist.Sort()
+	for idx := range s.trackedPlaylists {
+		if s.trackedPlaylists[idx].Id == msg.playlistId {
+			s.trackedPlaylists[idx] = *msg.playlist
+		}
+	}
+	s.sortPlaylists()
+	return wait
+}
+
+// refreshSummary returns the spinner and the number of playlists that are
+// refreshed, nothing if there are none
+func (s mainModel) refreshSummary() string {
+	if s.refreshing.count() == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" %s refreshing %d", s.refreshing.spinner(), s.refreshing.count())
+}
+
+// refreshColumn returns the progress of a running refresh of the
+// playlist, otherwise when it was refreshed
+func (s mainModel) refreshColumn(playlist *data.Playlist, now time.Time) string {
+	if s.refreshing.running(playlist.Id) {
+		return s.refreshing.label(playlist.Id)
+	}
+	return formatAgo(playlist.LastRefreshed, now)
+}
+
+func spinnerTick() tea.Cmd {
+	return tea.Tick(SPINNER_INTERVAL, func(time.Time) tea.Msg {
+		return msgSpinnerTick{}
+	})
+}
+
+// spin shows the next frame of the spinner until every refresh is done
+func (s *mainModel) spin() tea.Cmd {
+	if s.refreshing.count() == 0 {
+		s.refreshing.spinning = false
+		return nil
+	}
+	s.refreshing.frame++
+	return spinnerTick()
+}
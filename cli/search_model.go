@@ -2,8 +2,10 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/utility"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -20,7 +22,8 @@ type searchModel struct {
 	cursor         int
 
 	searchFocused bool
-	text         string
+	text          string
+	width         int
 
 	yt *data.YouTubeApi
 	dr data.DataRetriever
@@ -44,32 +47,44 @@ func (s searchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "backspace":
 			if len(s.text) > 0 {
-				s.text = s.text[:len(s.text)-1]
+				runes := []rune(s.text)
+				s.text = string(runes[:len(runes)-1])
 			}
 		case "esc":
-                        return nil, nil
+			return nil, nil
 
 		case "enter":
-                        return s, func() tea.Msg {
-                                playlists := s.yt.GetYoutubePlaylistsBySearch(s.text)
-                                return msgSearchedPlaylists{playlists}
-                        }
-                case "tab":
-                        return nil, func() tea.Msg {
-                                if len(s.foundPlaylists) <= 0 {
-                                        return nil
-                                }
-                                selectedPlaylist := s.foundPlaylists[s.cursor]
-                                videos := s.yt.GetAllPlaylistVideos(selectedPlaylist.Id)
-                                selectedPlaylist.Videos = videos
-                                return msgSearchedResult{selectedPlaylist}
-                        }
+			var search tea.Cmd
+			search = func() tea.Msg {
+				playlists, err := s.yt.GetYoutubePlaylistsBySearch(s.text)
+				if err != nil {
+					return errorStatus("could not search", err, search)
+				}
+				return msgSearchedPlaylists{playlists}
+			}
+			return s, search
+		case "tab":
+			if len(s.foundPlaylists) <= 0 {
+				return nil, nil
+			}
+			selectedPlaylist := s.foundPlaylists[s.cursor]
+			var add tea.Cmd
+			add = func() tea.Msg {
+				videos, err := s.yt.GetAllPlaylistVideos(selectedPlaylist.Id)
+				if err != nil {
+					return errorStatus("could not fetch the videos", err, add)
+				}
+				selectedPlaylist.Videos = videos
+				return msgSearchedResult{selectedPlaylist}
+			}
+			return nil, add
 		default:
-			msg := msg.String()
-			if len(msg) == 1 {
-				s.text += msg
+			if (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && !msg.Alt {
+				s.text += string(msg.Runes)
 			}
 		}
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
 	case msgSearchedPlaylists:
 		playlists := msg.playlists
 		s.foundPlaylists = playlists
@@ -85,12 +100,14 @@ func (s searchModel) View() string {
 		if idx == s.cursor {
 			cursor = ">"
 		}
-		text += fmt.Sprintf(" %s Title: %s Description: %s->\n", cursor, playlist.Title, playlist.Description)
+		line := fmt.Sprintf(" %s Title: %s Description: %s->", cursor, playlist.Title,
+			strings.ReplaceAll(playlist.Description, "\n", " "))
+		text += utility.Truncate(line, s.width) + "\n"
 	}
 
-        text += "\n\nKeymaps:\n"
-        text += "  * <enter> -> Search keyword\n"
-        text += "  * <tab>   -> Add playlist at cursor\n"
+	text += "\n\nKeymaps:\n"
+	text += "  * <enter> -> Search keyword\n"
+	text += "  * <tab>   -> Add playlist at cursor\n"
 
 	return text
 }
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/server"
+)
+
+// generateToken returns a random token for the http api
+func generateToken() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func runServe(ctx *commandContext, args []string) error {
+	flags := ctx.flags("serve")
+	addr := flags.String("addr", ctx.config.ServerAddress, "address to listen on")
+	token := flags.String("token", ctx.config.ServerToken, "token that authenticates api requests")
+	_, err := parseArgs(flags, args)
+	if err != nil {
+		return err
+	}
+
+	logger := log.New(os.Stderr, "tubevault: ", log.LstdFlags)
+
+	if *token == "" {
+		*token, err = generateToken()
+		if err != nil {
+			return err
+		}
+		logger.Printf("no ServerToken configured, using the generated token %s", *token)
+	}
+
+	// the api works without youtube, only search, add and refresh need it
+	var yt *data.YouTubeApi
+	if ctx.config.ApiKey != "" {
+		api, err := data.NewYouTubeApi(ctx.config.ApiKey)
+		if err != nil {
+			return err
+		}
+		yt = &api
+	}
+
+	handler, err := server.New(ctx.dr, yt, ctx.notifier, *token)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-signalCtx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	logger.Printf("serving the web interface on http://%s/ and the api on http://%s/api/", *addr, *addr)
+	err = httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
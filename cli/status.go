@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/baumple/watchvault/utility"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// severities of a status
+const (
+	SEVERITY_INFO    = "info"
+	SEVERITY_WARNING = "warning"
+	SEVERITY_ERROR   = "error"
+)
+
+const (
+	// STATUS_TIMEOUT is how long a status is shown
+	STATUS_TIMEOUT = 5 * time.Second
+	// ERROR_STATUS_TIMEOUT is how long an error is shown, it stays on key
+	// presses so it can be retried
+	ERROR_STATUS_TIMEOUT = 30 * time.Second
+)
+
+// msgStatus is shown below the current view until it times out
+type msgStatus struct {
+	text     string
+	severity string
+	// retry runs the failed command again, nil if there is nothing to retry
+	retry tea.Cmd
+}
+
+// msgStatusTimeout hides the status with the given id
+type msgStatusTimeout struct {
+	id int
+}
+
+// timeout returns how long the status is shown
+func (m msgStatus) timeout() time.Duration {
+	if m.severity == SEVERITY_ERROR {
+		return ERROR_STATUS_TIMEOUT
+	}
+	return STATUS_TIMEOUT
+}
+
+// errorStatus describes err as an error status
+func errorStatus(text string, err error, retry tea.Cmd) msgStatus {
+	return msgStatus{
+		text:     text + ": " + err.Error(),
+		severity: SEVERITY_ERROR,
+		retry:    retry,
+	}
+}
+
+// reportError returns a command that shows err as an error status
+func reportError(text string, err error) tea.Cmd {
+	return func() tea.Msg {
+		return errorStatus(text, err, nil)
+	}
+}
+
+// write runs fn as a command, a failure is shown as an error status that
+// can be retried
+func write(text string, fn func() error) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		err := fn()
+		if err != nil {
+			return errorStatus(text, err, cmd)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// showStatus shows status until it times out
+func (s *mainModel) showStatus(status msgStatus) tea.Cmd {
+	s.status = status
+	s.statusId++
+
+	id := s.statusId
+	return tea.Tick(status.timeout(), func(time.Time) tea.Msg {
+		return msgStatusTimeout{id}
+	})
+}
+
+// statusLine renders the status in the color of its severity
+func (s mainModel) statusLine() string {
+	text := s.status.text
+	if s.status.retry != nil {
+		text += " (" + s.keys.help(ACTION_RETRY) + " to retry)"
+	}
+
+	switch s.status.severity {
+	case SEVERITY_ERROR:
+		text = s.theme.error + "error: " + text + RESET
+	case SEVERITY_WARNING:
+		text = s.theme.highlight + "warning: " + text + RESET
+	default:
+		text = s.theme.highlight + text + RESET
+	}
+	return utility.Truncate(text, s.width)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const RESET = "\033[0m"
+
+// theme holds the escape sequences used to color the interface
+type theme struct {
+	// accent colors the scroll indicator
+	accent string
+	// selection marks the videos selected in visual mode
+	selection string
+	// highlight marks new videos and updated playlists
+	highlight string
+	// error colors failures in the status
+	error string
+}
+
+var themes = map[string]theme{
+	"default": {
+		accent:    "\033[34m",
+		selection: "\033[;5m",
+		highlight: "\033[33m",
+		error:     "\033[31m",
+	},
+	"mono": {
+		accent:    "\033[1m",
+		selection: "\033[7m",
+		highlight: "\033[1m",
+		error:     "\033[1;7m",
+	},
+	"green": {
+		accent:    "\033[32m",
+		selection: "\033[;5m",
+		highlight: "\033[92m",
+		error:     "\033[91m",
+	},
+}
+
+// getTheme returns the theme with the given name
+func getTheme(name string) (theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		names := []string{}
+		for name := range themes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return theme{}, fmt.Errorf("unknown theme %q, available themes: %s",
+			name, strings.Join(names, ", "))
+	}
+	return t, nil
+}
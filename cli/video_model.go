@@ -1,45 +1,518 @@
 package cli
 
 import (
+	"fmt"
+	"image"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/opener"
+	"github.com/baumple/watchvault/thumbnail"
+	"github.com/baumple/watchvault/utility"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/reflow/ansi"
+)
+
+const (
+	// THUMBNAIL_MAX_COLS is the widest a thumbnail is shown
+	THUMBNAIL_MAX_COLS = 48
+	// THUMBNAIL_DRAW_DELAY waits for the renderer to flush the frame the
+	// graphics are drawn over
+	THUMBNAIL_DRAW_DELAY = 50 * time.Millisecond
+	// THUMBNAIL_KITTY_ID is the id of the kitty image of the thumbnail
+	THUMBNAIL_KITTY_ID = 1
 )
 
+// msgThumbnail is sent when the thumbnail of a video was loaded
+type msgThumbnail struct {
+	videoId string
+	img     image.Image
+	err     error
+}
+
+// msgDrawThumbnail draws the kitty or sixel graphics of the thumbnail
+// after the frame was rendered
+type msgDrawThumbnail struct {
+	videoId string
+}
+
 type videoModel struct {
 	width  int
 	height int
+	// column is the first screen column of the view
+	column int
 	video  *data.Video
 
+	// pageIndex is the first visible line of the description
 	pageIndex int
+
+	chaptersFocused bool
+	chapterCursor   int
+
+	dr     data.DataRetriever
+	keys   keymap
+	theme  theme
+	opener string
+
+	thumbnails        *thumbnail.Cache
+	thumbnailProtocol string
+	thumbnail         image.Image
+	// output is the output of the program. The kitty and sixel graphics
+	// are written to it instead of the view, a write does not interleave
+	// with a frame of the renderer.
+	output io.Writer
 }
 
 func (v videoModel) Init() tea.Cmd {
-	return nil
+	if v.thumbnails == nil || v.thumbnailProtocol == thumbnail.PROTOCOL_NONE {
+		return nil
+	}
+
+	cache := v.thumbnails
+	videoId := v.video.VideoId
+	return func() tea.Msg {
+		img, err := cache.Get(videoId)
+		return msgThumbnail{videoId, img, err}
+	}
 }
 
 func (v videoModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		v.width = msg.Width
+		v.height = msg.Height
+		// the resize repaints the screen over the graphics
+		return v, v.scheduleDraw()
+
+	case msgThumbnail:
+		if msg.videoId != v.video.VideoId {
+			return v, nil
+		}
+		if msg.err != nil {
+			return v, func() tea.Msg {
+				return msgStatus{text: "could not load the thumbnail: " + msg.err.Error(), severity: SEVERITY_WARNING}
+			}
+		}
+		v.thumbnail = msg.img
+		return v, v.scheduleDraw()
+
+	case msgDrawThumbnail:
+		if msg.videoId != v.video.VideoId {
+			return v, nil
+		}
+		return v, v.draw()
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c":
-			return nil, tea.Quit
-		case "esc", "q":
-			return nil, nil
+		switch key := msg.String(); {
+		case v.keys.is(key, ACTION_QUIT):
+			return nil, tea.Sequence(v.clearGraphics(), tea.Quit)
+		case v.keys.is(key, ACTION_BACK):
+			return nil, v.clearGraphics()
+
+		case v.keys.is(key, ACTION_FOCUS):
+			v.chaptersFocused = !v.chaptersFocused && len(v.video.Chapters) > 0
+
+		case v.chaptersFocused:
+			return v, v.handleChapterInput(key)
+
+		case v.keys.is(key, ACTION_DOWN):
+			v.scroll(1)
+		case v.keys.is(key, ACTION_UP):
+			v.scroll(-1)
+		case v.keys.is(key, ACTION_PAGE_DOWN):
+			v.scroll(v.descriptionHeight())
+		case v.keys.is(key, ACTION_PAGE_UP):
+			v.scroll(-v.descriptionHeight())
+		case v.keys.is(key, ACTION_TOP):
+			v.pageIndex = 0
+		case v.keys.is(key, ACTION_BOTTOM):
+			v.scroll(len(v.description()))
+
+		case v.keys.is(key, ACTION_OPEN_VIDEO):
+			return v, open(v.opener, opener.ForVideo(v.video))
+
+		case v.keys.is(key, ACTION_TOGGLE_WATCHED):
+			v.video.SetWatched(!v.video.Watched)
+			dr := v.dr
+			playlistId := v.video.PlaylistId
+			id := v.video.Id
+			watched := v.video.Watched
+			return v, write("could not save the watched state", func() error {
+				return dr.UpdateVideoWatched(playlistId, id, watched)
+			})
 		}
 	}
 	return v, nil
 }
 
+// handleChapterInput moves the chapter cursor, opens the video at the
+// selected chapter and toggles its watched state
+func (v *videoModel) handleChapterInput(key string) tea.Cmd {
+	chapters := v.video.Chapters
+
+	switch {
+	case v.keys.is(key, ACTION_DOWN):
+		v.chapterCursor = min(v.chapterCursor+1, len(chapters)-1)
+	case v.keys.is(key, ACTION_UP):
+		v.chapterCursor = max(v.chapterCursor-1, 0)
+	case v.keys.is(key, ACTION_TOP):
+		v.chapterCursor = 0
+	case v.keys.is(key, ACTION_BOTTOM):
+		v.chapterCursor = len(chapters) - 1
+
+	case v.keys.is(key, ACTION_OPEN_VIDEO), v.keys.is(key, ACTION_DETAILS):
+		return open(v.opener, opener.ForChapter(v.video, chapters[v.chapterCursor]))
+
+	case v.keys.is(key, ACTION_TOGGLE_WATCHED):
+		chapters[v.chapterCursor].Watched = !chapters[v.chapterCursor].Watched
+
+		dr := v.dr
+		playlistId := v.video.PlaylistId
+		id := v.video.Id
+		stored := append([]data.Chapter{}, chapters...)
+		return write("could not save the chapters", func() error {
+			return dr.UpdateVideoChapters(playlistId, id, stored)
+		})
+	}
+	return nil
+}
+
+// scroll moves the description by n lines
+func (v *videoModel) scroll(n int) {
+	last := max(len(v.description())-v.descriptionHeight(), 0)
+	v.pageIndex = min(max(v.pageIndex+n, 0), last)
+}
+
+// graphics reports if the thumbnail is drawn outside of the view
+func (v videoModel) graphics() bool {
+	return v.thumbnail != nil && v.output != nil &&
+		(v.thumbnailProtocol == thumbnail.PROTOCOL_KITTY || v.thumbnailProtocol == thumbnail.PROTOCOL_SIXEL)
+}
+
+// thumbnailSize returns the size of the thumbnail in cells
+func (v videoModel) thumbnailSize() (int, int) {
+	cols := min(v.width-2, THUMBNAIL_MAX_COLS)
+	if v.thumbnail == nil || cols <= 0 {
+		return 0, 0
+	}
+	rows := min(thumbnail.Rows(v.thumbnail, cols), v.height/3)
+	return cols, rows
+}
+
+// scheduleDraw draws the graphics once the current frame is on screen
+func (v videoModel) scheduleDraw() tea.Cmd {
+	if !v.graphics() {
+		return nil
+	}
+	videoId := v.video.VideoId
+	return tea.Tick(THUMBNAIL_DRAW_DELAY, func(time.Time) tea.Msg {
+		return msgDrawThumbnail{videoId}
+	})
+}
+
+// draw writes the graphics to the output of the program at the blank
+// lines the view leaves for them. The renderer does not support graphics,
+// they would be cut off as too long lines. It skips the blank lines in
+// later frames as long as they do not change, so the graphics stay.
+func (v videoModel) draw() tea.Cmd {
+	if !v.graphics() {
+		return nil
+	}
+
+	img := v.thumbnail
+	protocol := v.thumbnailProtocol
+	cols, rows := v.thumbnailSize()
+	if rows <= 0 {
+		return nil
+	}
+	// the thumbnail follows the header, rows and columns are counted from 1
+	row := strings.Count(v.header(), "\n") + 1
+	column := v.column
+	output := v.output
+
+	return func() tea.Msg {
+		graphics := ""
+		switch protocol {
+		case thumbnail.PROTOCOL_KITTY:
+			encoded, err := thumbnail.Kitty(img, THUMBNAIL_KITTY_ID, cols, rows)
+			if err != nil {
+				return msgStatus{text: "could not show the thumbnail: " + err.Error(), severity: SEVERITY_WARNING}
+			}
+			graphics = encoded
+		case thumbnail.PROTOCOL_SIXEL:
+			graphics = thumbnail.Sixel(img, cols, rows)
+		}
+
+		// save the cursor, draw at the thumbnail and restore the cursor in
+		// a single write
+		fmt.Fprintf(output, "\0337\033[%d;%dH%s\0338", row, column+3, graphics)
+		return nil
+	}
+}
+
+// clearGraphics removes the graphics from the screen
+func (v videoModel) clearGraphics() tea.Cmd {
+	if !v.graphics() {
+		return nil
+	}
+
+	output := v.output
+	clearKitty := func() tea.Msg {
+		if v.thumbnailProtocol == thumbnail.PROTOCOL_KITTY {
+			fmt.Fprint(output, thumbnail.KittyDelete())
+		}
+		return nil
+	}
+	// sixels stay on lines the next view does not change
+	return tea.Sequence(clearKitty, tea.ClearScreen)
+}
+
 func (v videoModel) View() string {
-        text := ""
-        text += v.video.Description
+	text := v.header()
+
+	cols, rows := v.thumbnailSize()
+	if v.thumbnailProtocol == thumbnail.PROTOCOL_HALFBLOCK && rows > 0 {
+		blank := strings.Repeat(" ", cols)
+		for _, line := range thumbnail.HalfBlock(v.thumbnail, cols, rows) {
+			text += v.makeStyledLine(" "+blank, " "+line)
+		}
+	} else {
+		// the graphics are drawn over these lines
+		for i := 0; i < rows; i++ {
+			text += makeLine("", v.width)
+		}
+	}
+
+	text += v.chapters()
+
+	description := v.description()
+	visible := v.descriptionHeight()
+	title := "Description"
+	if len(description) > visible {
+		title = fmt.Sprintf("Description (%d-%d/%d)", v.pageIndex+1,
+			min(v.pageIndex+visible, len(description)), len(description))
+	}
+	text += makeSeparatorTitle(title, v.width)
+
+	for i := v.pageIndex; i < v.pageIndex+visible; i++ {
+		if i >= len(description) {
+			text += makeLine("", v.width)
+			continue
+		}
+		text += v.makeStyledLine(" "+description[i], " "+hyperlinks(description[i]))
+	}
+	text += makeBottomBar(v.width)
+
+	text += v.keymaps()
+
+	return text
+}
+
+// VIDEO_CHAPTER_LINES is the most chapters shown at once
+const VIDEO_CHAPTER_LINES = 8
+
+// chapters returns the list of chapters, a window around the cursor if
+// there are too many
+func (v videoModel) chapters() string {
+	chapters := v.video.Chapters
+	if len(chapters) == 0 {
+		return ""
+	}
+
+	title := "Chapters"
+	if v.chaptersFocused {
+		title = "Chapters (selected)"
+	}
+	text := makeSeparatorTitle(title, v.width)
+
+	visible := min(len(chapters), VIDEO_CHAPTER_LINES)
+	start := min(max(v.chapterCursor-visible/2, 0), len(chapters)-visible)
+	// the chapter that is playing at the stored position
+	current := v.video.ChapterAt(v.video.Position.Duration)
+
+	for i := start; i < start+visible; i++ {
+		chapter := chapters[i]
+
+		cursor := " "
+		if v.chaptersFocused && i == v.chapterCursor {
+			cursor = ">"
+		}
+		watched := "[ ]"
+		if chapter.Watched {
+			watched = "[X]"
+		}
+
+		plain := fmt.Sprintf(" %s %s %8s  %s", cursor, watched, formatDuration(chapter.Start.Duration), chapter.Title)
+		styled := plain
+		if i == current && v.video.Position.Duration > 0 {
+			styled = v.theme.highlight + plain + RESET
+		}
+		text += v.makeStyledLine(plain, styled)
+	}
+	return text
+}
+
+// keymaps returns the box with the keys of the detail screen
+func (v videoModel) keymaps() string {
+	text := makeTobBarTitle("Keymaps", v.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> return", v.keys.help(ACTION_BACK)), v.width)
+	if len(v.video.Chapters) > 0 {
+		text += makeLine(fmt.Sprintf("  * %-7s -> switch between chapters and description", v.keys.help(ACTION_FOCUS)), v.width)
+	}
+	text += makeLine(fmt.Sprintf("  * %-7s -> scroll", v.keys.help(ACTION_DOWN)+"/"+v.keys.help(ACTION_UP)), v.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> toggle watched", v.keys.help(ACTION_TOGGLE_WATCHED)), v.width)
+	text += makeLine(fmt.Sprintf("  * %-7s -> open video (at the chapter)", v.keys.help(ACTION_OPEN_VIDEO)), v.width)
+	text += makeBottomBar(v.width)
+	return text
+}
+
+// header returns the box with the title and the state of the video
+func (v videoModel) header() string {
+	video := v.video
+
+	text := makeTobBarTitle("Video", v.width)
+	for _, line := range utility.WordWrap(video.Title, v.width-4) {
+		text += makeLine(" "+line, v.width)
+	}
+	text += makeSeparator(v.width)
+
+	text += makeLine(" Published:  "+video.PublishedAt.Local().Format("2006-01-02 15:04"), v.width)
+
+	length := "unknown"
+	if video.Length.Duration > 0 {
+		length = formatDuration(video.Length.Duration)
+	}
+	text += makeLine(" Duration:   "+length, v.width)
+
+	watched := "no"
+	switch {
+	case video.Watched && !video.WatchedAt.IsZero():
+		watched = "yes, " + video.WatchedAt.Local().Format("2006-01-02 15:04")
+	case video.Watched:
+		watched = "yes"
+	case video.Position.Duration > 0:
+		watched = "no, stopped at " + formatDuration(video.Position.Duration)
+	}
+	text += makeLine(" Watched:    "+watched, v.width)
+
+	if status := getDownloadStatus(video); strings.TrimSpace(status) != "" {
+		text += makeLine(" Download:   "+status, v.width)
+	}
+
+	if strings.TrimSpace(video.Notes) != "" {
+		for idx, line := range utility.WordWrap(video.Notes, v.width-17) {
+			label := " Notes:      "
+			if idx > 0 {
+				label = "             "
+			}
+			text += makeLine(label+line, v.width)
+		}
+	}
+
+	url := video.URL()
+	text += v.makeStyledLine(" Link:       "+url, " Link:       "+hyperlink(url, url))
+
 	return text
 }
 
+// description returns the lines of the word wrapped description
+func (v videoModel) description() []string {
+	if strings.TrimSpace(v.video.Description) == "" {
+		return []string{"..."}
+	}
+	return utility.WordWrap(v.video.Description, v.width-4)
+}
+
+// descriptionHeight returns the number of description lines that fit
+// on the screen
+func (v videoModel) descriptionHeight() int {
+	_, rows := v.thumbnailSize()
+	used := strings.Count(v.header(), "\n") + rows +
+		strings.Count(v.chapters(), "\n") +
+		2 + // description title
+		1 + // bottom bar
+		strings.Count(v.keymaps(), "\n") +
+		1 // status
+	return max(v.height-used, 3)
+}
+
+// makeStyledLine is makeLine for text with escape sequences. plain is the
+// text without them, it is shown instead if the renderer would cut off
+// the styled text.
+func (v videoModel) makeStyledLine(plain string, styled string) string {
+	line := makeLine(styled, v.width)
+	// the renderer counts parts of the escape sequences as text
+	if ansi.PrintableRuneWidth(line) > v.width {
+		line = makeLine(plain, v.width)
+	}
+	return line
+}
+
+// formatDuration formats d like a video player, e.g. 1:02:03 or 4:05
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+[^\s<>".,;:!?)\]'"]`)
+
+// hyperlinks makes the urls in text clickable
+func hyperlinks(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, func(url string) string {
+		return hyperlink(url, url)
+	})
+}
+
+// hyperlink returns text as an OSC 8 link to url
+func hyperlink(url string, text string) string {
+	return "\033]8;;" + url + "\033\\" + text + "\033]8;;\033\\"
+}
+
+// previewVideo renders the details of video without loading its
+// thumbnail, an empty box if video is nil
+func previewVideo(video *data.Video, width int, height int, keys keymap, theme theme) string {
+	if video == nil {
+		return makeTobBarTitle("Video", width) +
+			makeLine(" no video selected", width) +
+			makeBottomBar(width)
+	}
+
+	preview := videoModel{
+		width:  width,
+		height: height,
+		video:  video,
+		keys:   keys,
+		theme:  theme,
+	}
+	return preview.View()
+}
+
+// resizeDetails gives the video details in model the given size and the
+// first screen column. Other models are returned as they are.
+func resizeDetails(model tea.Model, width int, height int, column int) (tea.Model, tea.Cmd) {
+	videoModel, ok := model.(videoModel)
+	if !ok {
+		return model, nil
+	}
+	videoModel.column = column
+	return videoModel.Update(tea.WindowSizeMsg{Width: width, Height: height})
+}
+
 func newVideoModel(video *data.Video, width int, height int) videoModel {
-        return videoModel {
-                width: width,
-                height: height,
-                video: video,
-        }
+	// videos stored before chapters were parsed
+	if video.Chapters == nil {
+		video.UpdateChapters()
+	}
+
+	return videoModel{
+		width:  width,
+		height: height,
+		video:  video,
+	}
 }
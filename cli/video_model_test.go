@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/thumbnail"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type ThumbnailTest struct {
+	protocol string
+	// inView is set if the thumbnail is part of the view, otherwise it is
+	// written to the output
+	inView bool
+}
+
+var thumbnailTests = []ThumbnailTest{
+	{thumbnail.PROTOCOL_HALFBLOCK, true},
+	{thumbnail.PROTOCOL_KITTY, false},
+	{thumbnail.PROTOCOL_SIXEL, false},
+}
+
+func TestThumbnailOutput(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+
+	for _, test := range thumbnailTests {
+		output := &bytes.Buffer{}
+		v := newVideoModel(&data.Video{Id: "v1", VideoId: "y1", Title: "Video"}, 60, 40)
+		v.thumbnailProtocol = test.protocol
+		v.output = output
+
+		model, cmd := v.Update(msgThumbnail{videoId: "y1", img: img})
+		v = model.(videoModel)
+		if (cmd == nil) != test.inView {
+			t.Fatalf("Wanted a scheduled draw %v for %s, got %v", !test.inView, test.protocol, cmd != nil)
+		}
+
+		_, cmd = v.Update(msgDrawThumbnail{videoId: "y1"})
+		if cmd != nil {
+			cmd()
+		}
+
+		view := v.View()
+		if strings.Contains(view, "▀") != test.inView {
+			t.Fatalf("Wanted the thumbnail in the view %v for %s, got %v", test.inView, test.protocol, !test.inView)
+		}
+		if (output.Len() > 0) == test.inView {
+			t.Fatalf("Wanted the thumbnail in the output %v for %s, got %q", !test.inView, test.protocol, output.String())
+		}
+	}
+}
+
+type VideoKeyTest struct {
+	key  tea.KeyMsg
+	quit bool
+}
+
+var videoKeyTests = []VideoKeyTest{
+	{tea.KeyMsg{Type: tea.KeyEsc}, false},
+	{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}, true},
+	{tea.KeyMsg{Type: tea.KeyCtrlC}, true},
+}
+
+func TestVideoKeys(t *testing.T) {
+	keys, err := newKeymap(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range videoKeyTests {
+		v := newVideoModel(&data.Video{Id: "v1", VideoId: "y1", Title: "Video"}, 60, 40)
+		v.keys = keys
+
+		model, cmd := v.Update(test.key)
+		if model != nil {
+			t.Fatalf("Wanted %s to close the video, got %v", test.key, model)
+		}
+		if (cmd != nil) != test.quit {
+			t.Fatalf("Wanted %s to quit %v, got %v", test.key, test.quit, cmd != nil)
+		}
+	}
+}
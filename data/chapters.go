@@ -0,0 +1,103 @@
+package data
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MIN_CHAPTERS is the number of timestamps a description needs to be
+// treated as a chapter list, a single timestamp is usually a reference
+const MIN_CHAPTERS = 2
+
+// Chapter is a section of a video taken from a timestamp in the
+// description
+type Chapter struct {
+	Title string
+	Start Duration
+	// Watched is set by the user, e.g. for long lectures
+	Watched bool
+}
+
+var (
+	// e.g. "00:00 Intro", "[1:02:03] - Outro" or "• 4:05 | Part"
+	leadingTimestamp = regexp.MustCompile(`^[-*•>]?\s*[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?\s*[-–—:|.]?\s*(.*)$`)
+	// e.g. "Intro - 00:00" or "Outro (1:02:03)"
+	trailingTimestamp = regexp.MustCompile(`^[-*•>]?\s*(.*?)\s*[-–—:|]?\s*[\[(]?((?:\d{1,2}:)?\d{1,2}:\d{2})[\])]?$`)
+)
+
+// ParseChapters returns the chapters listed in a video description,
+// sorted by their start. Descriptions with less than MIN_CHAPTERS
+// timestamps have no chapters.
+func ParseChapters(description string) []Chapter {
+	chapters := []Chapter{}
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+
+		timestamp, title := "", ""
+		if match := leadingTimestamp.FindStringSubmatch(line); match != nil {
+			timestamp, title = match[1], match[2]
+		} else if match := trailingTimestamp.FindStringSubmatch(line); match != nil {
+			timestamp, title = match[2], match[1]
+		} else {
+			continue
+		}
+
+		start, ok := parseTimestamp(timestamp)
+		if !ok {
+			continue
+		}
+		chapters = append(chapters, Chapter{Title: strings.TrimSpace(title), Start: Duration{start}})
+	}
+
+	if len(chapters) < MIN_CHAPTERS {
+		return nil
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].Start.Duration < chapters[j].Start.Duration
+	})
+	return chapters
+}
+
+// parseTimestamp parses "m:ss" and "h:mm:ss"
+func parseTimestamp(timestamp string) (time.Duration, bool) {
+	parts := strings.Split(timestamp, ":")
+	d := time.Duration(0)
+	for idx, part := range parts {
+		n, err := strconv.Atoi(part)
+		// minutes and seconds after the first part are below 60
+		if err != nil || (idx > 0 && n >= 60) {
+			return 0, false
+		}
+		d = d*60 + time.Duration(n)
+	}
+	return d * time.Second, true
+}
+
+// UpdateChapters parses the chapters of the description again and keeps
+// the watched state of chapters that start at the same time
+func (v *Video) UpdateChapters() {
+	chapters := ParseChapters(v.Description)
+	for idx := range chapters {
+		for _, old := range v.Chapters {
+			if old.Start == chapters[idx].Start {
+				chapters[idx].Watched = old.Watched
+			}
+		}
+	}
+	v.Chapters = chapters
+}
+
+// ChapterAt returns the index of the chapter that is playing at position,
+// -1 if there are no chapters
+func (v *Video) ChapterAt(position time.Duration) int {
+	current := -1
+	for idx, chapter := range v.Chapters {
+		if chapter.Start.Duration <= position {
+			current = idx
+		}
+	}
+	return current
+}
@@ -0,0 +1,65 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+type ChapterTest struct {
+	description string
+	expected    []data.Chapter
+}
+
+func chapter(title string, start time.Duration) data.Chapter {
+	return data.Chapter{Title: title, Start: data.Duration{Duration: start}}
+}
+
+var chapterTests = []ChapterTest{
+	{"00:00 Intro\n01:30 Main part\n1:02:03 Outro", []data.Chapter{
+		chapter("Intro", 0),
+		chapter("Main part", 90*time.Second),
+		chapter("Outro", time.Hour+2*time.Minute+3*time.Second),
+	}},
+	{"Chapters:\n[0:00] - Setup\n• 4:05 | Proof\n", []data.Chapter{
+		chapter("Setup", 0),
+		chapter("Proof", 4*time.Minute+5*time.Second),
+	}},
+	{"Welcome (0:00)\nSummary - 12:00", []data.Chapter{
+		chapter("Welcome", 0),
+		chapter("Summary", 12*time.Minute),
+	}},
+	{"The proof starts at 3:20, enjoy!", nil},
+	{"0:00 Start\n0:75 Invalid", nil},
+}
+
+func TestParseChapters(t *testing.T) {
+	for _, test := range chapterTests {
+		res := data.ParseChapters(test.description)
+		if len(res) != len(test.expected) {
+			t.Fatalf("Wanted %v for %q, got %v", test.expected, test.description, res)
+		}
+		for i := range res {
+			if res[i] != test.expected[i] {
+				t.Fatalf("Wanted %v for %q, got %v", test.expected, test.description, res)
+			}
+		}
+	}
+}
+
+func TestUpdateChapters(t *testing.T) {
+	video := data.Video{Description: "0:00 Intro\n5:00 Main"}
+	video.UpdateChapters()
+	video.Chapters[1].Watched = true
+
+	video.Description = "0:00 Intro\n2:00 Setup\n5:00 Main part"
+	video.UpdateChapters()
+	if len(video.Chapters) != 3 || !video.Chapters[2].Watched || video.Chapters[1].Watched {
+		t.Fatalf("Wanted the watched state to follow the start, got %v", video.Chapters)
+	}
+
+	if video.ChapterAt(3*time.Minute) != 1 {
+		t.Fatalf("Wanted chapter 1 at 3:00, got %d", video.ChapterAt(3*time.Minute))
+	}
+}
@@ -0,0 +1,390 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CONFIG_FILE = "config.json"
+
+	BACKEND_JSON = "json"
+)
+
+// Config holds every user setting of tubevault.
+// Values are read from the config file, then overridden by TUBEVAULT_*
+// environment variables and finally by command line flags.
+type Config struct {
+	ApiKey          string
+	Backend         string
+	VaultPath       string
+	Opener          string
+	RefreshInterval Duration
+	// RefreshJitter is the maximum random delay the daemon adds to every
+	// scheduled refresh so not all playlists are fetched at once
+	RefreshJitter Duration
+	// NewWindow is how long videos found by a refresh are marked as new
+	// if the playlist is not viewed, 0 marks them until it is viewed
+	NewWindow Duration
+	Theme     string
+	// Thumbnails is how thumbnails are shown: "auto", "kitty", "sixel",
+	// "halfblock" or "none"
+	Thumbnails string
+
+	// Keymap maps an action name (e.g. "quit") to a comma separated
+	// list of keys (e.g. "q,ctrl+c"). Actions not listed keep their
+	// default keys.
+	Keymap map[string]string
+
+	// ServerAddress is the address "tubevault serve" listens on
+	ServerAddress string
+	// ServerToken authenticates requests to the http api
+	ServerToken string
+
+	// Notifications are the sinks that are notified when a refresh
+	// finds new videos
+	Notifications []NotificationConfig
+
+	// Digest configures the periodic digest report
+	Digest DigestConfig
+
+	// Mpv configures the playback with progress tracking
+	Mpv MpvConfig
+
+	// Download configures the offline downloads
+	Download DownloadConfig
+
+	// path is the file the config was loaded from
+	path string
+}
+
+// NotificationConfig configures a notification sink.
+// Title and Body are text/template templates that are executed with a
+// notify.Event.
+type NotificationConfig struct {
+	// Type is one of "notify-send", "smtp", "ntfy" or "webhook"
+	Type string
+	// Playlists are the ids of the playlists this sink is notified about.
+	// An empty list means every playlist.
+	Playlists []string
+	Title     string
+	Body      string
+
+	// Url is the topic url of ntfy or the url of the webhook
+	Url     string
+	Token   string
+	Headers map[string]string
+	// Priority is the ntfy priority (1-5) or the notify-send urgency
+	Priority string
+
+	// Command replaces the notify-send executable
+	Command string
+
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// DigestConfig configures the digest report that the daemon mails
+type DigestConfig struct {
+	// Interval is how often the daemon mails a digest covering the time
+	// since the last one, 0 disables it
+	Interval Duration
+	// Format is "md", "html" or "text"
+	Format string
+	// Smtp is the mail server and the recipients of the digest. If Host
+	// is empty the first smtp notification is used.
+	Smtp NotificationConfig
+}
+
+// MpvConfig configures how videos are played in mpv
+type MpvConfig struct {
+	// Command is the mpv executable
+	Command string
+	// Args are passed to mpv in addition to the ipc socket
+	Args []string
+	// WatchedThreshold is the share of a video (0 to 1) after which it
+	// is marked as watched
+	WatchedThreshold float64
+}
+
+// DownloadConfig configures how videos are downloaded with yt-dlp
+type DownloadConfig struct {
+	// Command is the yt-dlp executable
+	Command string
+	// Format is the yt-dlp format selection
+	Format string
+	// Concurrency is the number of simultaneous downloads
+	Concurrency int
+	// Dir is where the videos are stored, a directory per playlist.
+	// Empty means the downloads directory of the vault.
+	Dir string
+	// Args are passed to yt-dlp in addition
+	Args []string
+}
+
+// DefaultConfig returns the config used when no config file exists.
+func DefaultConfig() Config {
+	return Config{
+		Backend:         BACKEND_JSON,
+		Opener:          "xdg-open",
+		RefreshInterval: Duration{time.Hour},
+		RefreshJitter:   Duration{5 * time.Minute},
+		NewWindow:       Duration{7 * 24 * time.Hour},
+		Theme:           "default",
+		Thumbnails:      "auto",
+		ServerAddress:   "127.0.0.1:8420",
+		Keymap:          map[string]string{},
+		Digest:          DigestConfig{Format: "md"},
+		Mpv:             MpvConfig{Command: "mpv", WatchedThreshold: 0.9},
+		Download: DownloadConfig{
+			Command:     "yt-dlp",
+			Format:      "bestvideo[height<=1080]+bestaudio/best",
+			Concurrency: 2,
+		},
+	}
+}
+
+// GetConfigPath returns the path of the config file. The environment
+// variable TUBEVAULT_CONFIG takes precedence over the default location.
+func GetConfigPath() (string, error) {
+	if path := os.Getenv("TUBEVAULT_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	configDir, err := GetConfigDirPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, CONFIG_FILE), nil
+}
+
+// LoadConfig reads the config file at path and applies the environment
+// variable overrides. A missing config file is not an error, the
+// defaults are used instead.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	config.path = path
+
+	file, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return config, err
+	}
+
+	if err == nil {
+		defer file.Close()
+		err = json.NewDecoder(file).Decode(&config)
+		if err != nil {
+			return config, fmt.Errorf("could not parse config file %s: %w", path, err)
+		}
+	}
+
+	if config.Keymap == nil {
+		config.Keymap = map[string]string{}
+	}
+
+	err = config.ApplyEnv()
+	return config, err
+}
+
+// ApplyEnv overrides the config values with the TUBEVAULT_* environment
+// variables that are set.
+func (c *Config) ApplyEnv() error {
+	if v, ok := os.LookupEnv("TUBEVAULT_API_KEY"); ok {
+		c.ApiKey = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_BACKEND"); ok {
+		c.Backend = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_VAULT"); ok {
+		c.VaultPath = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_OPENER"); ok {
+		c.Opener = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_THEME"); ok {
+		c.Theme = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_THUMBNAILS"); ok {
+		c.Thumbnails = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_SERVER_ADDRESS"); ok {
+		c.ServerAddress = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_SERVER_TOKEN"); ok {
+		c.ServerToken = v
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_REFRESH_INTERVAL"); ok {
+		d, err := ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("TUBEVAULT_REFRESH_INTERVAL: %w", err)
+		}
+		c.RefreshInterval = Duration{d}
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_REFRESH_JITTER"); ok {
+		d, err := ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("TUBEVAULT_REFRESH_JITTER: %w", err)
+		}
+		c.RefreshJitter = Duration{d}
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_NEW_WINDOW"); ok {
+		d, err := ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("TUBEVAULT_NEW_WINDOW: %w", err)
+		}
+		c.NewWindow = Duration{d}
+	}
+	if v, ok := os.LookupEnv("TUBEVAULT_DIGEST_INTERVAL"); ok {
+		d, err := ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("TUBEVAULT_DIGEST_INTERVAL: %w", err)
+		}
+		c.Digest.Interval = Duration{d}
+	}
+
+	return nil
+}
+
+// Validate checks that the config values are usable and explains how to
+// fix the ones that are not. The api key is checked by RequireApiKey,
+// since not every command talks to youtube.
+func (c *Config) Validate() error {
+	if c.Backend != BACKEND_JSON {
+		return fmt.Errorf("unknown backend %q, supported backends: %s",
+			c.Backend, BACKEND_JSON)
+	}
+
+	if c.Opener == "" {
+		return errors.New("no opener configured, set \"Opener\" in the " +
+			"config file, TUBEVAULT_OPENER or pass --opener")
+	}
+
+	if c.RefreshInterval.Duration < 0 {
+		return errors.New("the refresh interval must not be negative")
+	}
+
+	if c.RefreshJitter.Duration < 0 {
+		return errors.New("the refresh jitter must not be negative")
+	}
+
+	if c.NewWindow.Duration < 0 {
+		return errors.New("the new window must not be negative")
+	}
+
+	if c.Digest.Interval.Duration < 0 {
+		return errors.New("the digest interval must not be negative")
+	}
+
+	if c.Download.Concurrency < 1 {
+		return errors.New("the download concurrency must be at least 1")
+	}
+
+	if c.Mpv.WatchedThreshold <= 0 || c.Mpv.WatchedThreshold > 1 {
+		return fmt.Errorf("the mpv watched threshold must be between 0 and 1, got %v",
+			c.Mpv.WatchedThreshold)
+	}
+
+	return nil
+}
+
+// RequireApiKey explains how to set the api key if it is missing
+func (c *Config) RequireApiKey() error {
+	if c.ApiKey == "" {
+		return fmt.Errorf("no YouTube api key configured.\n"+
+			"Create one in the google developer console and either\n"+
+			"  * add \"ApiKey\": \"<key>\" to %s,\n"+
+			"  * set the TUBEVAULT_API_KEY environment variable or\n"+
+			"  * pass --api-key <key>", c.Path())
+	}
+	return nil
+}
+
+// Path returns the file the config was loaded from
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Duration is a time.Duration that is stored as a human readable string
+// like "1h30m" or "7d" in json.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// allow plain numbers which are interpreted as seconds
+		var seconds float64
+		if err := json.Unmarshal(b, &seconds); err != nil {
+			return fmt.Errorf("invalid duration %s", string(b))
+		}
+		d.Duration = time.Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// ParseDuration works like time.ParseDuration but also understands
+// days ("7d") and weeks ("2w").
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	if unit != 0 {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(unit)), nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// DigestSmtp returns the mail settings of the digest, falling back to the
+// first smtp notification
+func (c *Config) DigestSmtp() (NotificationConfig, error) {
+	if c.Digest.Smtp.Host != "" {
+		return c.Digest.Smtp, nil
+	}
+
+	for _, notification := range c.Notifications {
+		if notification.Type == "smtp" {
+			return notification, nil
+		}
+	}
+
+	return NotificationConfig{}, fmt.Errorf("no smtp server configured for the digest, "+
+		"add \"Digest\": {\"Smtp\": {...}} or an smtp notification to %s", c.Path())
+}
@@ -0,0 +1,82 @@
+package data_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+type DurationTest struct {
+	input    string
+	expected time.Duration
+}
+
+var durationTests = []DurationTest{
+	{"", 0},
+	{"30m", 30 * time.Minute},
+	{"1h30m", 90 * time.Minute},
+	{"7d", 7 * 24 * time.Hour},
+	{"2w", 14 * 24 * time.Hour},
+	{"0.5d", 12 * time.Hour},
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, test := range durationTests {
+		res, err := data.ParseDuration(test.input)
+		if err != nil {
+			t.Fatalf("Could not parse %q: %v", test.input, err)
+		}
+		if res != test.expected {
+			t.Fatalf("Wanted %v for %q, got %v", test.expected, test.input, res)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"ApiKey": "file", "Opener": "mpv", "RefreshInterval": "2h"}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TUBEVAULT_API_KEY", "env")
+	t.Setenv("TUBEVAULT_NEW_WINDOW", "3d")
+
+	config, err := data.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.ApiKey != "env" {
+		t.Fatalf("Wanted api key from environment, got %q", config.ApiKey)
+	}
+	if config.Opener != "mpv" {
+		t.Fatalf("Wanted opener mpv, got %q", config.Opener)
+	}
+	if config.RefreshInterval.Duration != 2*time.Hour {
+		t.Fatalf("Wanted refresh interval 2h, got %v", config.RefreshInterval)
+	}
+	if config.NewWindow.Duration != 3*24*time.Hour {
+		t.Fatalf("Wanted new window 3d from environment, got %v", config.NewWindow)
+	}
+	if config.Backend != data.BACKEND_JSON {
+		t.Fatalf("Wanted default backend, got %q", config.Backend)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateMissingApiKey(t *testing.T) {
+	t.Setenv("TUBEVAULT_API_KEY", "")
+	config, err := data.LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.RequireApiKey() == nil {
+		t.Fatal("Wanted an error for the missing api key")
+	}
+}
@@ -1,65 +1,274 @@
 package data
 
 import (
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
+	"time"
 )
 
-func GetSaveDirPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+const (
+	APP_NAME = "tubevault"
+
+	// LEGACY_DIR is the directory in HOME_DIR that was used to store the
+	// config and the vault before the XDG base directories were honoured
+	LEGACY_DIR = ".tubevault"
+
+	DIR_PERMISSIONS  = 0700
+	FILE_PERMISSIONS = 0600
+)
+
+var migrateOnce sync.Once
+var migrateErr error
+
+// xdgDir returns the tubevault directory inside the base directory given
+// by the environment variable env. If env is not set (or not absolute, as
+// required by the spec) HOME_DIR/fallback is used instead.
+// The directory is created if it does not exist yet.
+func xdgDir(env string, fallback string) (string, error) {
+	baseDir := os.Getenv(env)
+	if baseDir == "" || !filepath.IsAbs(baseDir) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		baseDir = filepath.Join(homeDir, fallback)
 	}
 
-	saveDir := filepath.Join(homeDir, ".tubevault")
-	err = os.Mkdir(saveDir, 0777)
-	if err != nil && !strings.Contains(err.Error(), "file exists") {
+	dir := filepath.Join(baseDir, APP_NAME)
+	err := os.MkdirAll(dir, DIR_PERMISSIONS)
+	if err != nil {
 		return "", err
 	}
 
-	return saveDir, nil
+	return dir, nil
+}
 
+// GetConfigDirPath returns the directory the config file is stored in.
+// Usually this is HOME_DIR/.config/tubevault
+func GetConfigDirPath() (string, error) {
+	err := migrateLegacyDir()
+	if err != nil {
+		return "", err
+	}
+	return xdgDir("XDG_CONFIG_HOME", ".config")
 }
 
-func GetSaveFile() (*os.File, error) {
-	dirPath, err := GetSaveDirPath()
+// GetSaveDirPath returns the directory of the default vault.
+// Usually this is HOME_DIR/.local/share/tubevault
+func GetSaveDirPath() (string, error) {
+	err := migrateLegacyDir()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
 
-	file, err := os.OpenFile(filepath.Join(dirPath, "config.json"), os.O_RDWR|os.O_CREATE, 0777)
+// GetCacheDirPath returns the directory for data that can be fetched
+// again. Usually this is HOME_DIR/.cache/tubevault
+func GetCacheDirPath() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
 
-	if err != nil {
-		return nil, err
+// GetDownloadDirPath returns the default directory of offline videos, next
+// to the playlists of the vault at vaultPath. An empty vaultPath is the
+// default vault, usually HOME_DIR/.local/share/tubevault/downloads.
+func GetDownloadDirPath(vaultPath string) (string, error) {
+	if vaultPath != "" {
+		return filepath.Join(vaultPath, DOWNLOAD_DIR), nil
 	}
 
-	return file, nil
+	saveDir, err := GetSaveDirPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(saveDir, DOWNLOAD_DIR), nil
 }
 
-// GetPlaylistDir returns a path to where playlists are stored.
-// Usually playlists are stored in HOME_DIR/.watchvault/playlists
+// GetPlaylistDir returns a path to where playlists of the default vault
+// are stored. Usually this is HOME_DIR/.local/share/tubevault/playlists
 func GetPlaylistDir() (string, error) {
 	saveDir, err := GetSaveDirPath()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
 	playlistDir := filepath.Join(saveDir, PLAYLIST_DIR)
 
-	err = os.Mkdir(playlistDir, 0777)
-	if err != nil && !strings.Contains(err.Error(), "file exists") {
+	err = os.MkdirAll(playlistDir, DIR_PERMISSIONS)
+	if err != nil {
 		return "", err
 	}
 
 	return playlistDir, nil
 }
 
+// migrateLegacyDir moves the config and the playlists of HOME_DIR/.tubevault
+// to the XDG base directories. Files that already exist in the new location
+// are left untouched. The legacy directory is removed once it is empty.
+func migrateLegacyDir() error {
+	migrateOnce.Do(func() {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			migrateErr = err
+			return
+		}
+
+		legacyDir := filepath.Join(homeDir, LEGACY_DIR)
+		info, err := os.Stat(legacyDir)
+		if err != nil || !info.IsDir() {
+			// nothing to migrate
+			return
+		}
+
+		configDir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+		if err != nil {
+			migrateErr = err
+			return
+		}
+
+		dataDir, err := xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+		if err != nil {
+			migrateErr = err
+			return
+		}
+
+		migrateErr = moveFile(filepath.Join(legacyDir, CONFIG_FILE), filepath.Join(configDir, CONFIG_FILE))
+		if migrateErr != nil {
+			return
+		}
+
+		legacyPlaylistDir := filepath.Join(legacyDir, PLAYLIST_DIR)
+		entries, err := os.ReadDir(legacyPlaylistDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			migrateErr = err
+			return
+		}
+
+		if len(entries) > 0 {
+			playlistDir := filepath.Join(dataDir, PLAYLIST_DIR)
+			migrateErr = os.MkdirAll(playlistDir, DIR_PERMISSIONS)
+			if migrateErr != nil {
+				return
+			}
+
+			for _, entry := range entries {
+				migrateErr = moveFile(filepath.Join(legacyPlaylistDir, entry.Name()),
+					filepath.Join(playlistDir, entry.Name()))
+				if migrateErr != nil {
+					return
+				}
+			}
+		}
+
+		// only removes the directories if they are empty
+		os.Remove(legacyPlaylistDir)
+		os.Remove(legacyDir)
+	})
+
+	return migrateErr
+}
+
+// restrictPermissions makes the vault in saveDir private. Playlists of
+// vaults created before the private permissions kept their permissions
+// until they were saved again, now they are restricted when the vault is
+// opened.
+func restrictPermissions(saveDir string, playlistDir string) error {
+	for _, dir := range []string{saveDir, playlistDir} {
+		err := restrictPermission(dir, DIR_PERMISSIONS)
+		if err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(playlistDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		err = restrictPermission(filepath.Join(playlistDir, entry.Name()), FILE_PERMISSIONS)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restrictPermission changes the permissions of path to perm if it grants
+// more than perm
+func restrictPermission(path string, perm os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&^perm == 0 {
+		return nil
+	}
+	return os.Chmod(path, perm)
+}
+
+// moveFile moves src to dst with private permissions. It does nothing if
+// src does not exist or dst already exists.
+func moveFile(src string, dst string) error {
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	err := os.Rename(src, dst)
+	if err == nil {
+		return os.Chmod(dst, FILE_PERMISSIONS)
+	}
+
+	// rename does not work across file systems, so copy the file instead
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, FILE_PERMISSIONS)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dstFile, srcFile)
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
 type DataRetriever interface {
 	GetPlaylists() ([]Playlist, error)
 	SavePlaylist(playlist *Playlist) error
 	DeletePlaylist(id string) error
 	UpdateVideoWatched(playlistId string, id string, watched bool) error
+	// UpdateVideoPosition stores where playback of the video stopped
+	UpdateVideoPosition(playlistId string, id string, position time.Duration) error
+	// UpdateVideoDownload stores the download state of the video
+	UpdateVideoDownload(playlistId string, id string, download Download) error
+	// UpdateVideoChapters stores the chapters of the video with their
+	// watched state
+	UpdateVideoChapters(playlistId string, id string, chapters []Chapter) error
+	// UpdateVideoNotes stores the notes of the video
+	UpdateVideoNotes(playlistId string, id string, notes string) error
+	// UpdatePlaylistViewed stores when the playlist was viewed and clears
+	// its Updated flag
+	UpdatePlaylistViewed(id string, viewed time.Time) error
+	// UpdatePlaylistSort stores the order the videos of the playlist are
+	// listed in
+	UpdatePlaylistSort(id string, mode string, descending bool) error
 	Close()
 }
-
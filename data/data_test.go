@@ -0,0 +1,173 @@
+package data_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+func TestMigrateLegacyDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	legacyDir := filepath.Join(home, data.LEGACY_DIR)
+	err := os.MkdirAll(filepath.Join(legacyDir, data.PLAYLIST_DIR), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(legacyDir, data.CONFIG_FILE), []byte(`{"ApiKey": "key"}`), 0777)
+	os.WriteFile(filepath.Join(legacyDir, data.PLAYLIST_DIR, "abc.json"), []byte(`{"Id": "abc"}`), 0777)
+
+	configDir, err := data.GetConfigDirPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlistDir, err := data.GetPlaylistDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(configDir, data.CONFIG_FILE),
+		filepath.Join(playlistDir, "abc.json"),
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Wanted %s to be migrated: %v", path, err)
+		}
+		if info.Mode().Perm() != data.FILE_PERMISSIONS {
+			t.Fatalf("Wanted permissions %o for %s, got %o", data.FILE_PERMISSIONS, path, info.Mode().Perm())
+		}
+	}
+
+	if _, err := os.Stat(legacyDir); err == nil {
+		t.Fatal("Wanted the empty legacy dir to be removed")
+	}
+}
+
+type PermissionTest struct {
+	path     string
+	before   os.FileMode
+	expected os.FileMode
+}
+
+func TestRestrictPermissions(t *testing.T) {
+	vault := filepath.Join(t.TempDir(), "vault")
+	playlistDir := filepath.Join(vault, data.PLAYLIST_DIR)
+	playlistFile := filepath.Join(playlistDir, "PL1.json")
+
+	err := os.MkdirAll(playlistDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(playlistFile, []byte(`{"Id": "PL1"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []PermissionTest{
+		{vault, 0755, data.DIR_PERMISSIONS},
+		{playlistDir, 0755, data.DIR_PERMISSIONS},
+		{playlistFile, 0644, data.FILE_PERMISSIONS},
+	}
+	// the vault was created before the permissions were private, chmod
+	// ignores the umask
+	for _, test := range tests {
+		err = os.Chmod(test.path, test.before)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = data.NewJsonRetriever(vault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		info, err := os.Stat(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != test.expected {
+			t.Fatalf("Wanted %s to have the permissions %o, got %o", test.path, test.expected, info.Mode().Perm())
+		}
+	}
+}
+
+type DownloadDirTest struct {
+	vaultPath string
+	expected  string
+}
+
+func TestDownloadDirPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	tests := []DownloadDirTest{
+		{"", filepath.Join(home, "data", data.APP_NAME, data.DOWNLOAD_DIR)},
+		{filepath.Join(home, "vault"), filepath.Join(home, "vault", data.DOWNLOAD_DIR)},
+	}
+	for _, test := range tests {
+		dir, err := data.GetDownloadDirPath(test.vaultPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dir != test.expected {
+			t.Fatalf("Wanted %s for vault %q, got %s", test.expected, test.vaultPath, dir)
+		}
+	}
+}
+
+func TestUpdatePlaylist(t *testing.T) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlist := data.Playlist{Id: "abc", Updated: true, Videos: []data.Video{{Id: "a"}}}
+	err = dr.SavePlaylist(&playlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// e.g. the daemon or the server stores a change in the meantime
+	err = dr.UpdateVideoWatched("abc", "a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	err = dr.UpdatePlaylistViewed("abc", viewed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := data.GetPlaylist(dr, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.LastViewed.Equal(viewed) || stored.Updated {
+		t.Fatalf("Wanted the playlist to be viewed at %v, got %v (updated %v)", viewed, stored.LastViewed, stored.Updated)
+	}
+	if !stored.Videos[0].Watched {
+		t.Fatal("Wanted the watched state stored in the meantime to be kept")
+	}
+
+	err = dr.UpdatePlaylistSort("abc", data.SORT_TITLE, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err = data.GetPlaylist(dr, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.SortMode != data.SORT_TITLE || !stored.SortDescending || !stored.Videos[0].Watched {
+		t.Fatalf("Wanted only the order to change, got %v", stored)
+	}
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"os"
+	"time"
+)
+
+const DOWNLOAD_DIR = "downloads"
+
+// download states of a video
+const (
+	DOWNLOAD_NONE        = ""
+	DOWNLOAD_QUEUED      = "queued"
+	DOWNLOAD_DOWNLOADING = "downloading"
+	DOWNLOAD_DONE        = "done"
+	DOWNLOAD_FAILED      = "failed"
+)
+
+// Download is the state of the offline copy of a video
+type Download struct {
+	Status string
+	// Progress is the downloaded share in percent
+	Progress float64
+	// File is the downloaded file
+	File string
+	// Error is the reason of a failed download
+	Error     string
+	UpdatedAt time.Time
+}
+
+// LocalFile returns the downloaded file of the video if it still exists
+func (v *Video) LocalFile() (string, bool) {
+	if v.Download.Status != DOWNLOAD_DONE || v.Download.File == "" {
+		return "", false
+	}
+	if _, err := os.Stat(v.Download.File); err != nil {
+		return "", false
+	}
+	return v.Download.File, true
+}
+
+// Pending returns whether the video waits for its download. Downloads that
+// were interrupted are pending again.
+func (v *Video) Pending() bool {
+	return v.Download.Status == DOWNLOAD_QUEUED || v.Download.Status == DOWNLOAD_DOWNLOADING
+}
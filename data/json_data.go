@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const PLAYLIST_DIR = "playlists"
@@ -13,6 +14,38 @@ type JsonRetriever struct {
 	playlistDir string
 }
 
+// NewJsonRetriever creates a JsonRetriever storing the vault in vaultPath.
+// An empty vaultPath uses the default save dir.
+func NewJsonRetriever(vaultPath string) (*JsonRetriever, error) {
+	if vaultPath == "" {
+		saveDir, err := GetSaveDirPath()
+		if err != nil {
+			return nil, err
+		}
+		playlistDir, err := GetPlaylistDir()
+		if err != nil {
+			return nil, err
+		}
+		return &JsonRetriever{}, restrictPermissions(saveDir, playlistDir)
+	}
+
+	playlistDir := filepath.Join(vaultPath, PLAYLIST_DIR)
+	err := os.MkdirAll(playlistDir, DIR_PERMISSIONS)
+	if err != nil {
+		return nil, err
+	}
+
+	err = restrictPermissions(vaultPath, playlistDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JsonRetriever{
+		saveDir:     vaultPath,
+		playlistDir: playlistDir,
+	}, nil
+}
+
 // getSaveDirPath returns a path to the save dir
 func (jr *JsonRetriever) getSaveDirPath() (string, error) {
 	if jr.saveDir != "" {
@@ -23,7 +56,7 @@ func (jr *JsonRetriever) getSaveDirPath() (string, error) {
 }
 
 // getPlaylistDir returns a path to where playlists are stored.
-// Usually playlists are stored in HOME_DIR/.tubevault/playlists
+// Usually playlists are stored in HOME_DIR/.local/share/tubevault/playlists
 // But also "cashes" the value
 func (jr *JsonRetriever) getPlaylistDir() (string, error) {
 	if jr.playlistDir != "" {
@@ -49,6 +82,10 @@ func (jr *JsonRetriever) GetPlaylists() ([]Playlist, error) {
 
 	playlists := []Playlist{}
 	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
 		playlistPath := filepath.Join(playlistDir, entry.Name())
 		file, err := os.Open(playlistPath)
 		if err != nil {
@@ -57,6 +94,7 @@ func (jr *JsonRetriever) GetPlaylists() ([]Playlist, error) {
 
 		playlist := Playlist{}
 		err = json.NewDecoder(file).Decode(&playlist)
+		file.Close()
 		if err != nil {
 			return nil, err
 		}
@@ -91,7 +129,9 @@ func (jr *JsonRetriever) DeletePlaylist(id string) error {
 	return err
 }
 
-// SavePlaylist writes creates a playlistDir and stores the playlist as json
+// SavePlaylist writes creates a playlistDir and stores the playlist as json.
+// The file is replaced atomically so the daemon and the interface never
+// read a half written playlist.
 func (jr *JsonRetriever) SavePlaylist(playlist *Playlist) error {
 	saveDir, err := jr.getPlaylistDir()
 	if err != nil {
@@ -99,30 +139,99 @@ func (jr *JsonRetriever) SavePlaylist(playlist *Playlist) error {
 	}
 
 	playlistPath := filepath.Join(saveDir, playlist.Id+".json")
-	playlistFile, err := os.OpenFile(playlistPath, os.O_RDWR|os.O_CREATE, 0777)
+	playlistFile, err := os.CreateTemp(saveDir, "."+playlist.Id+"-*.tmp")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(playlistFile.Name())
 
 	err = json.NewEncoder(playlistFile).Encode(playlist)
+	if closeErr := playlistFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	return os.Rename(playlistFile.Name(), playlistPath)
 }
 
 // UpdateVideoWatched implements DataRetriever.
 func (jr *JsonRetriever) UpdateVideoWatched(playlistId string, videoId string, watched bool) error {
+	return jr.updateVideo(playlistId, videoId, func(video *Video) {
+		video.SetWatched(watched)
+	})
+}
+
+// UpdateVideoPosition implements DataRetriever.
+func (jr *JsonRetriever) UpdateVideoPosition(playlistId string, videoId string, position time.Duration) error {
+	return jr.updateVideo(playlistId, videoId, func(video *Video) {
+		video.Position = Duration{position}
+	})
+}
+
+// UpdateVideoDownload implements DataRetriever.
+func (jr *JsonRetriever) UpdateVideoDownload(playlistId string, videoId string, download Download) error {
+	return jr.updateVideo(playlistId, videoId, func(video *Video) {
+		video.Download = download
+	})
+}
+
+// UpdateVideoChapters implements DataRetriever.
+func (jr *JsonRetriever) UpdateVideoChapters(playlistId string, videoId string, chapters []Chapter) error {
+	return jr.updateVideo(playlistId, videoId, func(video *Video) {
+		video.Chapters = chapters
+	})
+}
+
+// UpdateVideoNotes implements DataRetriever.
+func (jr *JsonRetriever) UpdateVideoNotes(playlistId string, videoId string, notes string) error {
+	return jr.updateVideo(playlistId, videoId, func(video *Video) {
+		video.Notes = notes
+	})
+}
+
+// UpdatePlaylistViewed implements DataRetriever.
+func (jr *JsonRetriever) UpdatePlaylistViewed(id string, viewed time.Time) error {
+	return jr.updatePlaylist(id, func(playlist *Playlist) {
+		playlist.LastViewed = viewed
+		playlist.Updated = false
+	})
+}
+
+// UpdatePlaylistSort implements DataRetriever.
+func (jr *JsonRetriever) UpdatePlaylistSort(id string, mode string, descending bool) error {
+	return jr.updatePlaylist(id, func(playlist *Playlist) {
+		playlist.SortMode = mode
+		playlist.SortDescending = descending
+	})
+}
+
+// updateVideo applies update to the video of the stored playlist
+func (jr *JsonRetriever) updateVideo(playlistId string, videoId string, update func(video *Video)) error {
+	return jr.updatePlaylist(playlistId, func(playlist *Playlist) {
+		for idx, video := range playlist.Videos {
+			if video.Id == videoId {
+				update(&playlist.Videos[idx])
+			}
+		}
+	})
+}
+
+// updatePlaylist applies update to the stored playlist, so changes other
+// programs stored in the meantime are kept
+func (jr *JsonRetriever) updatePlaylist(playlistId string, update func(playlist *Playlist)) error {
 	playlistDir, err := jr.getPlaylistDir()
 	if err != nil {
 		return err
 	}
 
 	playlistPath := filepath.Join(playlistDir, playlistId+".json")
-	playlistFile, err := os.OpenFile(playlistPath, os.O_RDWR|os.O_CREATE, 0777)
-	defer playlistFile.Close()
-
+	playlistFile, err := os.Open(playlistPath)
 	if err != nil {
 		return err
 	}
+	defer playlistFile.Close()
 
 	playlist := Playlist{}
 	err = json.NewDecoder(playlistFile).Decode(&playlist)
@@ -130,11 +239,7 @@ func (jr *JsonRetriever) UpdateVideoWatched(playlistId string, videoId string, w
 		return err
 	}
 
-	for idx, video := range playlist.Videos {
-		if video.Id == videoId {
-			playlist.Videos[idx].Watched = watched
-		}
-	}
+	update(&playlist)
 
 	err = jr.SavePlaylist(&playlist)
 
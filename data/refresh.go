@@ -0,0 +1,88 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotTracked is returned for playlists and videos that are not in the vault
+var ErrNotTracked = errors.New("not tracked")
+
+// RefreshPlaylist fetches the videos of the stored playlist with the given
+// id and merges the new ones into it. The playlist is read from the
+// DataRetriever after the videos were fetched, so changes made while
+// waiting for youtube (e.g. watched videos) are not overwritten.
+// It returns the updated playlist and the new videos.
+func RefreshPlaylist(dr DataRetriever, yt *YouTubeApi, id string) (*Playlist, []Video, error) {
+	return RefreshPlaylistProgress(dr, yt, id, nil)
+}
+
+// RefreshPlaylistProgress is RefreshPlaylist that reports the fetched
+// pages to progress, which may be nil
+func RefreshPlaylistProgress(dr DataRetriever, yt *YouTubeApi, id string, progress Progress) (*Playlist, []Video, error) {
+	videos, err := yt.GetAllPlaylistVideosProgress(id, progress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return MergePlaylistVideos(dr, id, videos)
+}
+
+// MergePlaylistVideos merges the fetched videos into the stored playlist
+// with the given id and stores it. It returns the updated playlist and the
+// new videos.
+func MergePlaylistVideos(dr DataRetriever, id string, videos []Video) (*Playlist, []Video, error) {
+	playlist, err := GetPlaylist(dr, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newVideos := playlist.MergeVideos(videos)
+
+	err = dr.SavePlaylist(playlist)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return playlist, newVideos, nil
+}
+
+// GetPlaylist returns the stored playlist with the given id
+func GetPlaylist(dr DataRetriever, id string) (*Playlist, error) {
+	playlists, err := dr.GetPlaylists()
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range playlists {
+		if playlists[idx].Id == id {
+			return &playlists[idx], nil
+		}
+	}
+
+	return nil, fmt.Errorf("playlist %s is %w", id, ErrNotTracked)
+}
+
+// Interval returns the refresh interval of the playlist. If the playlist
+// has no interval of its own defaultInterval is used.
+func (p *Playlist) Interval(defaultInterval time.Duration) time.Duration {
+	if p.RefreshInterval.Duration > 0 {
+		return p.RefreshInterval.Duration
+	}
+	return defaultInterval
+}
+
+// NextRefresh returns when the playlist should be refreshed next.
+// A playlist that was never refreshed is due immediately.
+func (p *Playlist) NextRefresh(defaultInterval time.Duration) time.Time {
+	if p.LastRefreshed.IsZero() {
+		return time.Time{}
+	}
+	return p.LastRefreshed.Add(p.Interval(defaultInterval))
+}
+
+// RefreshDue returns whether the playlist should be refreshed at now
+func (p *Playlist) RefreshDue(defaultInterval time.Duration, now time.Time) bool {
+	return !now.Before(p.NextRefresh(defaultInterval))
+}
@@ -0,0 +1,136 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+func TestRefreshDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	never := data.Playlist{}
+	if !never.RefreshDue(time.Hour, now) {
+		t.Fatal("Wanted a playlist that was never refreshed to be due")
+	}
+
+	recent := data.Playlist{LastRefreshed: now.Add(-30 * time.Minute)}
+	if recent.RefreshDue(time.Hour, now) {
+		t.Fatal("Wanted a recently refreshed playlist not to be due")
+	}
+
+	custom := data.Playlist{
+		LastRefreshed:   now.Add(-30 * time.Minute),
+		RefreshInterval: data.Duration{Duration: 10 * time.Minute},
+	}
+	if !custom.RefreshDue(time.Hour, now) {
+		t.Fatal("Wanted the playlist interval to override the default interval")
+	}
+	if next := custom.NextRefresh(time.Hour); !next.Equal(now.Add(-20 * time.Minute)) {
+		t.Fatalf("Wanted next refresh %v, got %v", now.Add(-20*time.Minute), next)
+	}
+}
+
+func TestMergeVideos(t *testing.T) {
+	playlist := data.Playlist{
+		Videos: []data.Video{{Id: "a", Title: "old", Watched: true}},
+	}
+
+	playlist.MergeVideos([]data.Video{{Id: "a", Title: "new"}, {Id: "b"}})
+
+	if playlist.Length() != 2 {
+		t.Fatalf("Wanted 2 videos, got %d", playlist.Length())
+	}
+	if !playlist.Updated {
+		t.Fatal("Wanted the playlist to be marked as updated")
+	}
+	if playlist.Videos[0].Title != "new" || !playlist.Videos[0].Watched {
+		t.Fatalf("Wanted the known video to be updated but stay watched, got %v", playlist.Videos[0])
+	}
+	if playlist.LastRefreshed.IsZero() {
+		t.Fatal("Wanted LastRefreshed to be set")
+	}
+}
+
+func TestMergeRemovedVideos(t *testing.T) {
+	playlist := data.Playlist{
+		Videos: []data.Video{{Id: "a", Watched: true}, {Id: "b"}},
+	}
+
+	playlist.MergeVideos([]data.Video{{Id: "b"}})
+	if playlist.Length() != 1 || len(playlist.Removed) != 1 || playlist.Removed[0].RemovedAt.IsZero() {
+		t.Fatalf("Wanted video a to be removed, got %v and %v", playlist.Videos, playlist.Removed)
+	}
+
+	newVideos := playlist.MergeVideos([]data.Video{{Id: "a"}, {Id: "b"}})
+	if len(newVideos) != 0 || playlist.Length() != 2 || len(playlist.Removed) != 0 {
+		t.Fatalf("Wanted video a to be restored, got %v and %v", playlist.Videos, playlist.Removed)
+	}
+	if video := playlist.FindVideo("a"); !video.Watched || !video.RemovedAt.IsZero() {
+		t.Fatalf("Wanted the restored video to stay watched, got %v", video)
+	}
+}
+
+func TestVideoState(t *testing.T) {
+	lastViewed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	initial := data.Video{}
+	found := data.Video{DiscoveredAt: lastViewed.Add(time.Hour)}
+	seen := data.Video{DiscoveredAt: lastViewed.Add(-time.Hour)}
+	if initial.NewSince(lastViewed) || !found.NewSince(lastViewed) || seen.NewSince(lastViewed) {
+		t.Fatal("Wanted only videos found after the last visit to be new")
+	}
+	if !found.NewSince(time.Time{}) {
+		t.Fatal("Wanted found videos of a playlist that was never viewed to be new")
+	}
+	now := lastViewed.Add(3 * 24 * time.Hour)
+	if !found.IsNew(lastViewed, 0, now) || !found.IsNew(lastViewed, 7*24*time.Hour, now) {
+		t.Fatal("Wanted a found video to be new within the window")
+	}
+	if found.IsNew(lastViewed, 24*time.Hour, now) || seen.IsNew(lastViewed, 0, now) {
+		t.Fatal("Wanted videos outside of the window or seen before not to be new")
+	}
+
+	started := data.Video{Position: data.Duration{Duration: time.Minute}}
+	if !started.InProgress() {
+		t.Fatal("Wanted a started video to be in progress")
+	}
+	started.SetWatched(true)
+	if started.InProgress() {
+		t.Fatal("Wanted a watched video not to be in progress")
+	}
+}
+
+func TestPlaylistProgress(t *testing.T) {
+	lastViewed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	minutes := func(n int) data.Duration {
+		return data.Duration{Duration: time.Duration(n) * time.Minute}
+	}
+
+	playlist := data.Playlist{
+		LastViewed: lastViewed,
+		Videos: []data.Video{
+			{Id: "a", Channel: "Uploader", Length: minutes(30), Watched: true},
+			{Id: "b", Length: minutes(20), Position: minutes(5)},
+			{Id: "c", Length: minutes(10), DiscoveredAt: lastViewed.Add(time.Hour)},
+		},
+	}
+
+	if remaining := playlist.Remaining(); remaining != 25*time.Minute {
+		t.Fatalf("Wanted 25m remaining, got %v", remaining)
+	}
+	if count := playlist.NewCount(0, lastViewed.Add(48*time.Hour)); count != 1 {
+		t.Fatalf("Wanted one new video, got %d", count)
+	}
+	if count := playlist.NewCount(24*time.Hour, lastViewed.Add(48*time.Hour)); count != 0 {
+		t.Fatalf("Wanted no new videos after the window, got %d", count)
+	}
+	if channel := playlist.ChannelName(); channel != "Uploader" {
+		t.Fatalf("Wanted the channel of the first video, got %q", channel)
+	}
+	playlist.Channel = "Owner"
+	if channel := playlist.ChannelName(); channel != "Owner" {
+		t.Fatalf("Wanted the channel of the playlist, got %q", channel)
+	}
+}
@@ -0,0 +1,116 @@
+package data
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// orders the videos of a playlist can be sorted in
+const (
+	SORT_POSITION  = "position"
+	SORT_PUBLISHED = "published"
+	SORT_ADDED     = "added"
+	SORT_TITLE     = "title"
+	SORT_DURATION  = "duration"
+	SORT_WATCHED   = "watched"
+)
+
+// SORT_MODES lists the sort modes in the order they are cycled through
+var SORT_MODES = []string{SORT_POSITION, SORT_PUBLISHED, SORT_ADDED, SORT_TITLE, SORT_DURATION, SORT_WATCHED}
+
+// CheckSortMode returns an error for unknown sort modes
+func CheckSortMode(mode string) error {
+	for _, known := range SORT_MODES {
+		if mode == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown sort mode %q, supported: %s", mode, strings.Join(SORT_MODES, ", "))
+}
+
+// NextSortMode returns the sort mode after mode in SORT_MODES
+func NextSortMode(mode string) string {
+	for idx, known := range SORT_MODES {
+		if mode == known {
+			return SORT_MODES[(idx+1)%len(SORT_MODES)]
+		}
+	}
+	return SORT_MODES[0]
+}
+
+// SortLabel describes the sort order of the playlist, e.g. "title, descending"
+func (p *Playlist) SortLabel() string {
+	mode := p.SortMode
+	if mode == "" {
+		mode = SORT_POSITION
+	}
+	if p.SortDescending {
+		return mode + ", descending"
+	}
+	return mode + ", ascending"
+}
+
+// Sort sorts the videos in the order chosen for the playlist
+func (p *Playlist) Sort() {
+	p.SortBy(p.SortMode, p.SortDescending)
+}
+
+// SortBy sorts the videos by mode. The sort is stable, videos that are
+// equal keep their order.
+func (p *Playlist) SortBy(mode string, descending bool) {
+	compare := compareVideos(mode)
+	sort.SliceStable(p.Videos, func(i, j int) bool {
+		if descending {
+			return compare(&p.Videos[j], &p.Videos[i]) < 0
+		}
+		return compare(&p.Videos[i], &p.Videos[j]) < 0
+	})
+}
+
+// compareVideos returns the comparison of the sort mode
+func compareVideos(mode string) func(a *Video, b *Video) int {
+	switch mode {
+	case SORT_PUBLISHED:
+		return func(a *Video, b *Video) int {
+			return a.PublishedAt.Compare(b.PublishedAt)
+		}
+	case SORT_ADDED:
+		return func(a *Video, b *Video) int {
+			return a.addedAt().Compare(b.addedAt())
+		}
+	case SORT_TITLE:
+		return func(a *Video, b *Video) int {
+			return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		}
+	case SORT_DURATION:
+		return func(a *Video, b *Video) int {
+			return cmp.Compare(a.Length.Duration, b.Length.Duration)
+		}
+	case SORT_WATCHED:
+		// unwatched videos first
+		return func(a *Video, b *Video) int {
+			if a.Watched == b.Watched {
+				return 0
+			}
+			if a.Watched {
+				return 1
+			}
+			return -1
+		}
+	}
+	return func(a *Video, b *Video) int {
+		return cmp.Compare(a.Index, b.Index)
+	}
+}
+
+// addedAt returns when the video was added to the playlist. Videos stored
+// before it was recorded used PublishedAt for it.
+func (v *Video) addedAt() time.Time {
+	if v.AddedAt.IsZero() {
+		return v.PublishedAt
+	}
+	return v.AddedAt
+}
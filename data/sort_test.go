@@ -0,0 +1,73 @@
+package data_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+type SortTest struct {
+	mode       string
+	descending bool
+
+	expected string
+}
+
+var sortTests = []SortTest{
+	{data.SORT_POSITION, false, "abcd"},
+	{data.SORT_POSITION, true, "dcba"},
+	{data.SORT_PUBLISHED, false, "cabd"},
+	// a and b were published at the same time and keep their order
+	{data.SORT_PUBLISHED, true, "dabc"},
+	{data.SORT_ADDED, false, "dcba"},
+	{data.SORT_TITLE, false, "bdac"},
+	{data.SORT_DURATION, true, "cabd"},
+	{data.SORT_WATCHED, false, "acbd"},
+}
+
+func sortFixture() data.Playlist {
+	day := func(n int) time.Time {
+		return time.Date(2024, 1, n, 0, 0, 0, 0, time.UTC)
+	}
+	minutes := func(n int) data.Duration {
+		return data.Duration{Duration: time.Duration(n) * time.Minute}
+	}
+
+	return data.Playlist{Videos: []data.Video{
+		{Id: "a", Index: 0, Title: "lecture 2", PublishedAt: day(2), AddedAt: day(9), Length: minutes(30)},
+		{Id: "b", Index: 1, Title: "Intro", PublishedAt: day(2), AddedAt: day(8), Length: minutes(10), Watched: true},
+		{Id: "c", Index: 2, Title: "Summary", PublishedAt: day(1), AddedAt: day(7), Length: minutes(90)},
+		{Id: "d", Index: 3, Title: "Lecture 1", PublishedAt: day(3), AddedAt: day(6), Length: minutes(5), Watched: true},
+	}}
+}
+
+func TestSortBy(t *testing.T) {
+	for _, test := range sortTests {
+		playlist := sortFixture()
+		playlist.SortBy(test.mode, test.descending)
+
+		order := ""
+		for _, video := range playlist.Videos {
+			order += video.Id
+		}
+		if order != test.expected {
+			t.Fatalf("Wanted %s sorted by %s (descending %v), got %s", test.expected, test.mode, test.descending, order)
+		}
+	}
+}
+
+func TestSortModes(t *testing.T) {
+	if data.NextSortMode(data.SORT_WATCHED) != data.SORT_POSITION {
+		t.Fatal("Wanted the sort modes to wrap around")
+	}
+	if data.NextSortMode("") != data.SORT_POSITION {
+		t.Fatal("Wanted the default sort mode for an empty mode")
+	}
+
+	err := data.CheckSortMode("random")
+	if err == nil || !strings.Contains(err.Error(), data.SORT_TITLE) {
+		t.Fatalf("Wanted an error listing the sort modes, got %v", err)
+	}
+}
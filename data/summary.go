@@ -0,0 +1,81 @@
+package data
+
+import (
+	"time"
+)
+
+// PlaylistSummary is the json representation of a playlist without its
+// videos, used by the headless commands and the http api
+type PlaylistSummary struct {
+	Id            string    `json:"id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Url           string    `json:"url"`
+	Channel       string    `json:"channel"`
+	Watched       int       `json:"watched"`
+	Total         int       `json:"total"`
+	Updated       bool      `json:"updated"`
+	LastRefreshed time.Time `json:"last_refreshed"`
+	Tags          []string  `json:"tags"`
+}
+
+// VideoSummary is the json representation of a video
+type VideoSummary struct {
+	Id          string    `json:"id"`
+	VideoId     string    `json:"video_id"`
+	PlaylistId  string    `json:"playlist_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Url         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	Watched     bool      `json:"watched"`
+	// DiscoveredAt is omitted for videos that were not found by a refresh
+	DiscoveredAt *time.Time `json:"discovered_at,omitempty"`
+	// WatchedAt is omitted for unwatched videos and videos watched
+	// before it was recorded
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+}
+
+// Summary returns the json representation of the playlist
+func (p *Playlist) Summary() PlaylistSummary {
+	return PlaylistSummary{
+		Id:            p.Id,
+		Title:         p.Title,
+		Description:   p.Description,
+		Url:           p.URL(),
+		Channel:       p.ChannelName(),
+		Watched:       p.WatchedCount(),
+		Total:         p.Length(),
+		Updated:       p.Updated,
+		LastRefreshed: p.LastRefreshed,
+		Tags:          append([]string{}, p.Tags...),
+	}
+}
+
+// Summary returns the json representation of the video
+func (v *Video) Summary() VideoSummary {
+	var discoveredAt *time.Time
+	if !v.DiscoveredAt.IsZero() {
+		discoveredAt = &v.DiscoveredAt
+	}
+	var watchedAt *time.Time
+	if !v.WatchedAt.IsZero() {
+		watchedAt = &v.WatchedAt
+	}
+
+	return VideoSummary{
+		Id:          v.Id,
+		VideoId:     v.VideoId,
+		PlaylistId:  v.PlaylistId,
+		Title:       v.Title,
+		Description: v.Description,
+		Url:         v.URL(),
+		PublishedAt: v.PublishedAt,
+		Watched:     v.Watched,
+
+		DiscoveredAt: discoveredAt,
+		WatchedAt:    watchedAt,
+		Notes:        v.Notes,
+	}
+}
@@ -1,9 +1,11 @@
 package data
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
@@ -15,61 +17,34 @@ type YouTubeApi struct {
 	youtubeService *youtube.Service
 }
 
-type config struct {
-	ApiKey string
-}
-
-func getApiKey() string {
-	file, err := GetSaveFile()
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	c := config{}
-
-	err = json.NewDecoder(file).Decode(&c)
-
-	if c.ApiKey == "" {
-		fmt.Print("No api key provided.\nPlease enter your api key: ")
-		fmt.Scan(&c.ApiKey)
-		err = json.NewEncoder(file).Encode(&c)
-		if err != nil {
-			log.Fatalf("Could not store api key: %s", err.Error())
-		}
-
-	} else if err != nil {
-		log.Fatal(err)
+// NewYouTubeApi creates a client for the youtube data api
+// authenticated with the given api key.
+func NewYouTubeApi(apiKey string) (YouTubeApi, error) {
+	if apiKey == "" {
+		return YouTubeApi{}, errors.New("no youtube api key provided")
 	}
 
-	return c.ApiKey
-}
-
-func NewYouTubeApi() YouTubeApi {
-	// TODO: Prompt user to enter id and store
-	// it in a config file
-
-	apiKey := getApiKey()
 	ctx := context.Background()
 	youtubeService, err := youtube.NewService(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
-		log.Fatalf("Could not initiate youtube api: %v\n", err.Error())
+		return YouTubeApi{}, fmt.Errorf("could not initiate youtube api: %w", err)
 	}
 	return YouTubeApi{
 		youtubeService: youtubeService,
-	}
+	}, nil
 }
 
-func (yt *YouTubeApi) GetYoutubePlaylistsById(id string) []Playlist {
+func (yt *YouTubeApi) GetYoutubePlaylistsById(id string) ([]Playlist, error) {
 	playlistsResp, err := yt.youtubeService.Playlists.List([]string{"snippet", "id"}).Id(id).Do()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	playlists := []Playlist{}
 	for _, playlistResp := range playlistsResp.Items {
 		time, err := time.Parse(time.RFC3339, playlistResp.Snippet.PublishedAt)
 		if err != nil {
-			log.Fatal(err)
+			return nil, fmt.Errorf("could not parse field PublishedAt: %w", err)
 		}
 
 		var playlist Playlist
@@ -77,13 +52,14 @@ func (yt *YouTubeApi) GetYoutubePlaylistsById(id string) []Playlist {
 		playlist.Title = playlistResp.Snippet.Title
 		playlist.PublishedAt = time
 		playlist.Description = playlistResp.Snippet.Description
+		playlist.Channel = playlistResp.Snippet.ChannelTitle
 		playlists = append(playlists, playlist)
 	}
 
-	return playlists
+	return playlists, nil
 }
 
-func (yt *YouTubeApi) GetYoutubePlaylistsBySearch(search string) []Playlist {
+func (yt *YouTubeApi) GetYoutubePlaylistsBySearch(search string) ([]Playlist, error) {
 	playlistsResp, err := yt.youtubeService.Search.List([]string{"id",
 		"snippet"}).
 		Q(search).
@@ -91,14 +67,14 @@ func (yt *YouTubeApi) GetYoutubePlaylistsBySearch(search string) []Playlist {
 		Do()
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	playlists := []Playlist{}
 	for _, playlistResp := range playlistsResp.Items {
 		publishedAt, err := time.Parse(time.RFC3339, playlistResp.Snippet.PublishedAt)
 		if err != nil {
-			log.Fatal(err)
+			return nil, fmt.Errorf("could not parse field PublishedAt: %w", err)
 		}
 
 		var playlist Playlist
@@ -106,44 +82,93 @@ func (yt *YouTubeApi) GetYoutubePlaylistsBySearch(search string) []Playlist {
 		playlist.Id = playlistResp.Id.PlaylistId
 		playlist.Description = playlistResp.Snippet.Description
 		playlist.PublishedAt = publishedAt
+		playlist.Channel = playlistResp.Snippet.ChannelTitle
 
 		playlists = append(playlists, playlist)
 	}
 
-	return playlists
+	return playlists, nil
+}
+
+func (yt *YouTubeApi) GetAllPlaylistVideos(id string) ([]Video, error) {
+	return yt.GetAllPlaylistVideosProgress(id, nil)
+}
+
+// PAGE_SIZE is the most videos youtube returns per request
+const PAGE_SIZE = 50
+
+// Progress is called after every page of videos that was fetched. pages
+// is the number of pages youtube expects.
+type Progress func(page int, pages int)
+
+// PageCount returns the number of pages of a playlist with total videos
+func PageCount(total int64) int {
+	return max(int((total+PAGE_SIZE-1)/PAGE_SIZE), 1)
 }
 
-func (yt *YouTubeApi) GetAllPlaylistVideos(id string) []Video {
+// GetAllPlaylistVideosProgress is GetAllPlaylistVideos that reports the
+// fetched pages to progress, which may be nil
+func (yt *YouTubeApi) GetAllPlaylistVideosProgress(id string, progress Progress) ([]Video, error) {
 	videos := []Video{}
 
 	nextPageToken := ""
+	page := 0
 
 	for {
 		videosResp, err := yt.
 			youtubeService.
 			PlaylistItems.
-			List([]string{"id", "snippet"}).
+			List([]string{"id", "snippet", "contentDetails"}).
 			PlaylistId(id).
-			MaxResults(100).
+			MaxResults(PAGE_SIZE).
 			PageToken(nextPageToken).
 			Do()
 
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
+		}
+
+		page++
+		if progress != nil {
+			pages := page
+			if videosResp.PageInfo != nil {
+				pages = max(PageCount(videosResp.PageInfo.TotalResults), page)
+			}
+			progress(page, pages)
 		}
 
 		for _, videoResp := range videosResp.Items {
-			publishedAt, err := time.Parse(time.RFC3339, videoResp.Snippet.PublishedAt)
+			// the item was published when it was added to the playlist
+			addedAt, err := time.Parse(time.RFC3339, videoResp.Snippet.PublishedAt)
 			if err != nil {
-				log.Fatal("Could not parse field PublishedAt: " + err.Error())
+				return nil, fmt.Errorf("could not parse field PublishedAt: %w", err)
+			}
+
+			publishedAt := addedAt
+			if videoResp.ContentDetails != nil && videoResp.ContentDetails.VideoPublishedAt != "" {
+				publishedAt, err = time.Parse(time.RFC3339, videoResp.ContentDetails.VideoPublishedAt)
+				if err != nil {
+					return nil, fmt.Errorf("could not parse field VideoPublishedAt: %w", err)
+				}
 			}
+
+			videoId := ""
+			if videoResp.Snippet.ResourceId != nil {
+				videoId = videoResp.Snippet.ResourceId.VideoId
+			}
+
 			videos = append(videos, Video{
 				Id:          videoResp.Id,
+				VideoId:     videoId,
 				Title:       videoResp.Snippet.Title,
 				Description: videoResp.Snippet.Description,
 				PublishedAt: publishedAt,
+				AddedAt:     addedAt,
+				Index:       int(videoResp.Snippet.Position),
+				Channel:     videoResp.Snippet.VideoOwnerChannelTitle,
 				PlaylistId:  videoResp.Snippet.PlaylistId,
 				Watched:     false,
+				Chapters:    ParseChapters(videoResp.Snippet.Description),
 			})
 		}
 
@@ -153,7 +178,141 @@ func (yt *YouTubeApi) GetAllPlaylistVideos(id string) []Video {
 		}
 	}
 
-	return videos
+	err := yt.getVideoLengths(videos)
+	if err != nil {
+		return nil, err
+	}
+
+	return videos, nil
+}
+
+// getVideoLengths sets the length of the videos, the playlist items do
+// not contain it
+func (yt *YouTubeApi) getVideoLengths(videos []Video) error {
+	for start := 0; start < len(videos); start += 50 {
+		batch := videos[start:min(start+50, len(videos))]
+
+		ids := []string{}
+		for _, video := range batch {
+			if video.VideoId != "" {
+				ids = append(ids, video.VideoId)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		resp, err := yt.youtubeService.Videos.List([]string{"contentDetails"}).Id(ids...).Do()
+		if err != nil {
+			return err
+		}
+
+		for _, item := range resp.Items {
+			if item.ContentDetails == nil {
+				continue
+			}
+			// a length youtube reports in an unknown format stays unknown
+			// instead of failing the whole refresh
+			length, err := ParseIsoDuration(item.ContentDetails.Duration)
+			if err != nil {
+				continue
+			}
+			for idx := range batch {
+				if batch[idx].VideoId == item.Id {
+					batch[idx].Length = Duration{length}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ParseIsoDuration parses the ISO 8601 durations youtube uses for the
+// length of videos, e.g. "PT1H2M3S" or "P1DT2H"
+func ParseIsoDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(s, "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+	}
+	timeUnits := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+
+	d := time.Duration(0)
+	number := ""
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T' && number == "":
+			units = timeUnits
+		default:
+			unit, ok := units[c]
+			if !ok || number == "" {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			d += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// GetPlaylist fetches the playlist with the given id including all of
+// its videos
+func (yt *YouTubeApi) GetPlaylist(id string) (Playlist, error) {
+	playlists, err := yt.GetYoutubePlaylistsById(id)
+	if err != nil {
+		return Playlist{}, err
+	}
+	if len(playlists) == 0 {
+		return Playlist{}, fmt.Errorf("playlist %s does not exist", id)
+	}
+
+	playlist := playlists[0]
+	playlist.Videos, err = yt.GetAllPlaylistVideos(playlist.Id)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	return playlist, nil
+}
+
+// ParsePlaylistId returns the playlist id of a playlist url like
+// https://youtube.com/playlist?list=<id>. Anything that is not a url is
+// treated as an id.
+func ParsePlaylistId(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") && !strings.Contains(s, "?") {
+		return s, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	id := u.Query().Get("list")
+	if id == "" {
+		return "", fmt.Errorf("%s does not contain a playlist id", s)
+	}
+
+	return id, nil
 }
 
 type Playlist struct {
@@ -161,8 +320,36 @@ type Playlist struct {
 	Title       string
 	Description string
 	PublishedAt time.Time
-	Videos      []Video
-	Updated     bool `json:"-"`
+	// Channel is the name of the channel that owns the playlist
+	Channel string
+	Videos  []Video
+	// Updated is set when a refresh found new videos and cleared when
+	// the playlist is viewed
+	Updated bool
+	// LastViewed is the time the playlist was last opened in the
+	// interface, videos discovered after it are new
+	LastViewed time.Time
+
+	// LastRefreshed is the time of the last successful refresh
+	LastRefreshed time.Time
+	// RefreshInterval overrides the configured refresh interval for
+	// this playlist if it is not 0
+	RefreshInterval Duration
+
+	// Tags group playlists, e.g. for feeds
+	Tags []string
+	// Opener overrides the configured opener for this playlist if it is
+	// not empty
+	Opener string
+
+	// SortMode is the order the videos are listed in, one of the SORT_*
+	// constants. Empty means SORT_POSITION.
+	SortMode       string
+	SortDescending bool
+
+	// Removed holds the videos that were removed from the playlist on
+	// youtube, for the digest
+	Removed []Video
 }
 
 func (p *Playlist) String() string {
@@ -174,82 +361,261 @@ func (p *Playlist) Length() int {
 	return len(p.Videos)
 }
 
-func (p *Playlist) Sort() {
-	quicksort(0, p.Length()-1, p.Videos)
+// FetchUpdate checks if the playlist has new videos
+// it sets the flag Playlist.Updated to true
+func (p *Playlist) FetchUpdate(yt *YouTubeApi) error {
+	videos, err := yt.GetAllPlaylistVideos(p.Id) // Get the newer playlist information
+	if err != nil {
+		return err
+	}
 
+	p.MergeVideos(videos)
+	return nil
 }
 
-// quicksort sorts a given video slice
-func quicksort(lowerBounds int, upperBounds int, videos []Video) {
-	if lowerBounds >= upperBounds || lowerBounds < 0 {
-		return
+// MergeVideos appends the videos that are not yet in the playlist and
+// updates the title and description of the known ones. Known videos that
+// are missing in videos are moved to Playlist.Removed.
+// It sets the flag Playlist.Updated to true if there were new videos and
+// returns them.
+func (p *Playlist) MergeVideos(videos []Video) []Video {
+	newVideos := []Video{}
+
+	now := time.Now()
+
+	// videos that were removed and added again are restored with their
+	// watched state
+	for _, video := range videos {
+		for ridx := range p.Removed {
+			if p.Removed[ridx].Id == video.Id {
+				restored := p.Removed[ridx]
+				restored.RemovedAt = time.Time{}
+				p.Videos = append(p.Videos, restored)
+				p.Removed = append(p.Removed[:ridx], p.Removed[ridx+1:]...)
+				break
+			}
+		}
+	}
+
+	// go through every video and check whether it is already in the list
+	for idx, video := range videos {
+		isNew := false
+		for idx := range p.Videos {
+			knownVideo := &p.Videos[idx]
+			isNew = isNew || video.Id == knownVideo.Id
+			if knownVideo.Id == video.Id {
+				knownVideo.Title = video.Title
+				knownVideo.Description = video.Description
+				knownVideo.VideoId = video.VideoId
+				knownVideo.Length = video.Length
+				knownVideo.PublishedAt = video.PublishedAt
+				knownVideo.AddedAt = video.AddedAt
+				knownVideo.Index = video.Index
+				knownVideo.Channel = video.Channel
+				knownVideo.UpdateChapters()
+			}
+		}
+		if !isNew { // if it is not, append it
+			p.Updated = true
+			videos[idx].DiscoveredAt = now
+			p.Videos = append(p.Videos, videos[idx])
+			newVideos = append(newVideos, videos[idx])
+		}
 	}
 
-	p := partition(lowerBounds, upperBounds, videos)
+	// keep the videos that are still in the playlist
+	kept := []Video{}
+	for _, knownVideo := range p.Videos {
+		found := false
+		for _, video := range videos {
+			found = found || video.Id == knownVideo.Id
+		}
+		if found {
+			kept = append(kept, knownVideo)
+			continue
+		}
+		knownVideo.RemovedAt = now
+		p.Removed = append(p.Removed, knownVideo)
+	}
+	p.Videos = kept
 
-	quicksort(lowerBounds, p-1, videos)
-	quicksort(p+1, upperBounds, videos)
+	p.LastRefreshed = now
+	return newVideos
 }
 
-// 1 3 2 4 pivot = 4, pivotI = 0
-// -> 4 3 2 1 -> sort => pivot = 1 pivotI = 0
-// -> 4<->1 because 4(the pivotI) > 1 (the pivot)
-
-func partition(lowerBounds int, upperBounds int, videos []Video) int {
-	pivotElement := videos[upperBounds]
-	pivotPos := lowerBounds
+// FindVideo returns the video with the given id or youtube video id
+func (p *Playlist) FindVideo(id string) *Video {
+	for idx := range p.Videos {
+		if p.Videos[idx].Id == id || p.Videos[idx].VideoId == id {
+			return &p.Videos[idx]
+		}
+	}
+	return nil
+}
 
-	for i := lowerBounds; i < upperBounds; i++ {
-		comp := videos[i].PublishedAt.Compare(pivotElement.PublishedAt)
-		if comp <= 0 {
-			swap(i, pivotPos, videos)
-			pivotPos++
+// WatchedCount returns the number of watched videos
+func (p *Playlist) WatchedCount() int {
+	watched := 0
+	for _, video := range p.Videos {
+		if video.Watched {
+			watched++
 		}
 	}
+	return watched
+}
 
-	// this is required to avoid an endless loop when
-	// the right most item is the biggest item -> the pivot wont change and
-	// nothing will move
-	swap(pivotPos, upperBounds, videos)
-	return pivotPos
+// ChannelName returns the channel of the playlist. Playlists stored before
+// it was recorded use the channel of their first video.
+func (p *Playlist) ChannelName() string {
+	if p.Channel != "" {
+		return p.Channel
+	}
+	for _, video := range p.Videos {
+		if video.Channel != "" {
+			return video.Channel
+		}
+	}
+	return ""
 }
 
-func swap[T any](a int, b int, nums []T) {
-	temp := nums[a]
-	nums[a] = nums[b]
-	nums[b] = temp
+// Remaining returns how long it takes to watch the unwatched videos,
+// started videos count from their position
+func (p *Playlist) Remaining() time.Duration {
+	remaining := time.Duration(0)
+	for _, video := range p.Videos {
+		if !video.Watched {
+			remaining += max(video.Length.Duration-video.Position.Duration, 0)
+		}
+	}
+	return remaining
 }
 
-// FetchUpdate checks if the playlist has new videos
-// it sets the flag Video.New to true
-func (p *Playlist) FetchUpdate(yt *YouTubeApi) {
-	videos := yt.GetAllPlaylistVideos(p.Id) // Get the newer playlist information
+// NewCount returns the number of videos that are new at now, see
+// Video.IsNew
+func (p *Playlist) NewCount(window time.Duration, now time.Time) int {
+	count := 0
+	for idx := range p.Videos {
+		if p.Videos[idx].IsNew(p.LastViewed, window, now) {
+			count++
+		}
+	}
+	return count
+}
 
-	// go through every video and check whether it is already in the list
-	for idx, video := range videos {
-		isNew := false
-		for idx := range p.Videos {
-                        knownVideo := &p.Videos[idx]
-			isNew = isNew || video.Id == knownVideo.Id
-                        if knownVideo.Id == video.Id {
-                                knownVideo.Title = video.Title
-                                knownVideo.Description = video.Description
-                        }
+// HasTag returns whether the playlist is tagged with tag
+func (p *Playlist) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
 		}
-		if !isNew { // if it is not, append it
-			p.Updated = true
-			p.Videos = append(p.Videos, videos[idx])
+	}
+	return false
+}
+
+// AddTag tags the playlist with tag if it is not tagged with it yet
+func (p *Playlist) AddTag(tag string) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || p.HasTag(tag) {
+		return
+	}
+	p.Tags = append(p.Tags, tag)
+}
+
+// RemoveTag removes tag from the playlist
+func (p *Playlist) RemoveTag(tag string) {
+	tags := []string{}
+	for _, t := range p.Tags {
+		if t != tag {
+			tags = append(tags, t)
 		}
 	}
+	p.Tags = tags
+}
+
+// URL returns the link to the playlist on youtube
+func (p *Playlist) URL() string {
+	return "https://youtube.com/playlist?list=" + p.Id
 }
 
 type Video struct {
-	Id          string
+	// Id is the id of the playlist item
+	Id string
+	// VideoId is the id of the youtube video
+	VideoId     string
 	Title       string
 	Description string
 	PublishedAt time.Time
 	PlaylistId  string
 	Watched     bool
+	// AddedAt is the time the video was added to the playlist
+	AddedAt time.Time
+	// Index is the position of the video in the playlist on youtube
+	Index int
+	// Channel is the name of the channel that uploaded the video
+	Channel string
+	// Length is the duration of the video, 0 if it is unknown
+	Length Duration
+	// Chapters are parsed from the timestamps in the description
+	Chapters []Chapter
+
+	// DiscoveredAt is the time a refresh found the video. It is zero for
+	// videos that were in the playlist when it was added.
+	DiscoveredAt time.Time
+	// WatchedAt is the time the video was marked as watched, zero for
+	// unwatched videos and videos watched before it was recorded
+	WatchedAt time.Time
+	// RemovedAt is the time a refresh noticed that the video is no longer
+	// in the playlist
+	RemovedAt time.Time
+	// Position is where playback stopped the last time, 0 if the video
+	// was not started or played to the end
+	Position Duration
+
+	// Download is the state of the offline copy
+	Download Download
+
+	// Notes are written by the user
+	Notes string
+}
+
+// SetWatched marks the video as watched or unwatched and records when it
+// was watched
+func (v *Video) SetWatched(watched bool) {
+	if watched && !v.Watched {
+		v.WatchedAt = time.Now()
+	}
+	if !watched {
+		v.WatchedAt = time.Time{}
+	}
+	v.Watched = watched
+}
+
+// InProgress reports whether the video was started but not watched
+func (v *Video) InProgress() bool {
+	return !v.Watched && v.Position.Duration > 0
+}
+
+// NewSince reports whether a refresh found the video after t
+func (v *Video) NewSince(t time.Time) bool {
+	return !v.DiscoveredAt.IsZero() && v.DiscoveredAt.After(t)
+}
+
+// IsNew reports whether a refresh found the video after the playlist was
+// viewed at lastViewed and at most window before now. A window of 0 keeps
+// videos new until the playlist is viewed.
+func (v *Video) IsNew(lastViewed time.Time, window time.Duration, now time.Time) bool {
+	if !v.NewSince(lastViewed) {
+		return false
+	}
+	return window <= 0 || now.Sub(v.DiscoveredAt) < window
+}
+
+// URL returns the link to the video on youtube
+func (v *Video) URL() string {
+	if v.VideoId == "" {
+		return "https://youtube.com/playlist?list=" + v.PlaylistId
+	}
+	return "https://youtube.com/watch?v=" + v.VideoId + "&list=" + v.PlaylistId
 }
 
 func (v *Video) String() string {
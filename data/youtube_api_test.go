@@ -0,0 +1,89 @@
+package data_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+type PlaylistIdTest struct {
+	input    string
+	expected string
+}
+
+var playlistIdTests = []PlaylistIdTest{
+	{"PLabc", "PLabc"},
+	{" PLabc ", "PLabc"},
+	{"https://youtube.com/playlist?list=PLabc", "PLabc"},
+	{"https://www.youtube.com/watch?v=xyz&list=PLabc&index=2", "PLabc"},
+}
+
+func TestParsePlaylistId(t *testing.T) {
+	for _, test := range playlistIdTests {
+		res, err := data.ParsePlaylistId(test.input)
+		if err != nil {
+			t.Fatalf("Could not parse %q: %v", test.input, err)
+		}
+		if res != test.expected {
+			t.Fatalf("Wanted %q, got %q", test.expected, res)
+		}
+	}
+
+	if _, err := data.ParsePlaylistId("https://youtube.com/watch?v=xyz"); err == nil {
+		t.Fatal("Wanted an error for a url without a playlist")
+	}
+}
+
+type IsoDurationTest struct {
+	input    string
+	expected time.Duration
+}
+
+var isoDurationTests = []IsoDurationTest{
+	{"PT45S", 45 * time.Second},
+	{"PT1H2M3S", time.Hour + 2*time.Minute + 3*time.Second},
+	{"PT10M", 10 * time.Minute},
+	{"P1DT2H", 26 * time.Hour},
+	{"P0D", 0},
+}
+
+func TestParseIsoDuration(t *testing.T) {
+	for _, test := range isoDurationTests {
+		res, err := data.ParseIsoDuration(test.input)
+		if err != nil {
+			t.Fatalf("Could not parse %q: %v", test.input, err)
+		}
+		if res != test.expected {
+			t.Fatalf("Wanted %v for %q, got %v", test.expected, test.input, res)
+		}
+	}
+
+	for _, input := range []string{"", "1H", "PT5", "PTH", "PT1X"} {
+		if _, err := data.ParseIsoDuration(input); err == nil {
+			t.Fatalf("Wanted an error for %q", input)
+		}
+	}
+}
+
+type PageCountTest struct {
+	total    int64
+	expected int
+}
+
+var pageCountTests = []PageCountTest{
+	{0, 1},
+	{1, 1},
+	{50, 1},
+	{51, 2},
+	{600, 12},
+}
+
+func TestPageCount(t *testing.T) {
+	for _, test := range pageCountTests {
+		res := data.PageCount(test.total)
+		if res != test.expected {
+			t.Fatalf("Wanted %d pages for %d videos, got %d", test.expected, test.total, res)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+const (
+	FORMAT_MARKDOWN = "md"
+	FORMAT_HTML     = "html"
+	FORMAT_TEXT     = "text"
+
+	// STATE_FILE stores when the daemon sent the last digest
+	STATE_FILE = "digest.json"
+)
+
+// Report is the digest of the changes to the vault between Since and Until
+type Report struct {
+	Since     time.Time        `json:"since"`
+	Until     time.Time        `json:"until"`
+	Playlists []PlaylistReport `json:"playlists"`
+}
+
+// PlaylistReport holds the changes to a single playlist
+type PlaylistReport struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Url   string `json:"url"`
+
+	New     []data.VideoSummary `json:"new"`
+	Watched []data.VideoSummary `json:"watched"`
+	Removed []data.VideoSummary `json:"removed"`
+
+	// the completion at Since and at Until
+	WatchedBefore int `json:"watched_before"`
+	TotalBefore   int `json:"total_before"`
+	WatchedAfter  int `json:"watched_after"`
+	TotalAfter    int `json:"total_after"`
+}
+
+// Empty returns whether nothing changed in the reported time
+func (r *Report) Empty() bool {
+	return len(r.Playlists) == 0
+}
+
+// CompletionBefore returns the share of watched videos at Since in percent
+func (p *PlaylistReport) CompletionBefore() int {
+	return percent(p.WatchedBefore, p.TotalBefore)
+}
+
+// CompletionAfter returns the share of watched videos at Until in percent
+func (p *PlaylistReport) CompletionAfter() int {
+	return percent(p.WatchedAfter, p.TotalAfter)
+}
+
+// CompletionChanged returns whether the share of watched videos changed
+func (p *PlaylistReport) CompletionChanged() bool {
+	return p.CompletionBefore() != p.CompletionAfter()
+}
+
+func percent(part int, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return part * 100 / total
+}
+
+// between returns whether t is set and within (since, until]
+func between(t time.Time, since time.Time, until time.Time) bool {
+	return !t.IsZero() && t.After(since) && !t.After(until)
+}
+
+// existedAt returns whether the video was part of the playlist at t.
+// Videos without DiscoveredAt were there when the playlist was added.
+func existedAt(video *data.Video, t time.Time) bool {
+	return video.DiscoveredAt.IsZero() || !video.DiscoveredAt.After(t)
+}
+
+// watchedAt returns whether the video was watched at t as far as the
+// vault knows. Unwatching a video forgets when it was watched.
+func watchedAt(video *data.Video, t time.Time) bool {
+	return video.Watched && !video.WatchedAt.After(t)
+}
+
+// New builds the report of the changes to playlists between since and until.
+// Playlists without changes are left out.
+func New(playlists []data.Playlist, since time.Time, until time.Time) Report {
+	report := Report{
+		Since:     since,
+		Until:     until,
+		Playlists: []PlaylistReport{},
+	}
+
+	for idx := range playlists {
+		playlist := &playlists[idx]
+		playlist.Sort()
+
+		entry := PlaylistReport{
+			Id:      playlist.Id,
+			Title:   playlist.Title,
+			Url:     playlist.URL(),
+			New:     []data.VideoSummary{},
+			Watched: []data.VideoSummary{},
+			Removed: []data.VideoSummary{},
+		}
+
+		for vidx := range playlist.Videos {
+			video := &playlist.Videos[vidx]
+			if between(video.DiscoveredAt, since, until) {
+				entry.New = append(entry.New, video.Summary())
+			}
+			if between(video.WatchedAt, since, until) {
+				entry.Watched = append(entry.Watched, video.Summary())
+			}
+
+			if existedAt(video, until) {
+				entry.TotalAfter++
+				if watchedAt(video, until) {
+					entry.WatchedAfter++
+				}
+			}
+			if existedAt(video, since) {
+				entry.TotalBefore++
+				if watchedAt(video, since) {
+					entry.WatchedBefore++
+				}
+			}
+		}
+
+		for vidx := range playlist.Removed {
+			video := &playlist.Removed[vidx]
+			if !video.RemovedAt.After(since) || !existedAt(video, since) {
+				continue
+			}
+
+			entry.TotalBefore++
+			if watchedAt(video, since) {
+				entry.WatchedBefore++
+			}
+			if between(video.RemovedAt, since, until) {
+				entry.Removed = append(entry.Removed, video.Summary())
+			}
+		}
+
+		if len(entry.New) == 0 && len(entry.Watched) == 0 && len(entry.Removed) == 0 && !entry.CompletionChanged() {
+			continue
+		}
+		report.Playlists = append(report.Playlists, entry)
+	}
+
+	return report
+}
+
+// Subject returns the subject line of the report as email
+func (r *Report) Subject() string {
+	return fmt.Sprintf("tubevault digest %s - %s", r.Since.Format(time.DateOnly), r.Until.Format(time.DateOnly))
+}
+
+// ContentType returns the mime type of the given format
+func ContentType(format string) string {
+	if format == FORMAT_HTML {
+		return "text/html"
+	}
+	return "text/plain"
+}
+
+// CheckFormat returns an error for unknown formats
+func CheckFormat(format string) error {
+	switch format {
+	case FORMAT_MARKDOWN, FORMAT_HTML, FORMAT_TEXT:
+		return nil
+	}
+	return fmt.Errorf("unknown digest format %q, supported formats: %s, %s, %s",
+		format, FORMAT_MARKDOWN, FORMAT_HTML, FORMAT_TEXT)
+}
+
+var funcs = map[string]any{
+	"date": func(t time.Time) string {
+		return t.Local().Format(time.DateOnly)
+	},
+	// markdown escapes the characters that would format a title
+	"markdown": strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `*`, `\*`, `_`, `\_`, "`", "\\`").Replace,
+}
+
+const MARKDOWN_TEMPLATE = `# tubevault digest
+{{date .Since}} - {{date .Until}}
+{{if .Empty}}
+Nothing happened in your playlists.
+{{end}}{{range .Playlists}}
+## [{{markdown .Title}}]({{.Url}})
+
+Completion: {{.CompletionBefore}}% → {{.CompletionAfter}}% ({{.WatchedAfter}}/{{.TotalAfter}} watched)
+{{if .New}}
+### New videos
+{{range .New}}- [{{markdown .Title}}]({{.Url}})
+{{end}}{{end}}{{if .Watched}}
+### Watched
+{{range .Watched}}- [{{markdown .Title}}]({{.Url}})
+{{end}}{{end}}{{if .Removed}}
+### Removed
+{{range .Removed}}- {{markdown .Title}}
+{{end}}{{end}}{{end}}`
+
+const TEXT_TEMPLATE = `tubevault digest {{date .Since}} - {{date .Until}}
+{{if .Empty}}
+Nothing happened in your playlists.
+{{end}}{{range .Playlists}}
+{{.Title}}
+  completion: {{.CompletionBefore}}% -> {{.CompletionAfter}}% ({{.WatchedAfter}}/{{.TotalAfter}} watched)
+{{range .New}}  + {{.Title}} {{.Url}}
+{{end}}{{range .Watched}}  ✓ {{.Title}}
+{{end}}{{range .Removed}}  - {{.Title}}
+{{end}}{{end}}`
+
+const HTML_TEMPLATE = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>tubevault digest</title></head>
+<body style="font-family: sans-serif">
+<h1>tubevault digest</h1>
+<p>{{date .Since}} - {{date .Until}}</p>
+{{if .Empty}}<p>Nothing happened in your playlists.</p>
+{{end}}{{range .Playlists}}<h2><a href="{{.Url}}">{{.Title}}</a></h2>
+<p>Completion: {{.CompletionBefore}}% &rarr; {{.CompletionAfter}}% ({{.WatchedAfter}}/{{.TotalAfter}} watched)</p>
+{{if .New}}<h3>New videos</h3>
+<ul>
+{{range .New}}<li><a href="{{.Url}}">{{.Title}}</a></li>
+{{end}}</ul>
+{{end}}{{if .Watched}}<h3>Watched</h3>
+<ul>
+{{range .Watched}}<li><a href="{{.Url}}">{{.Title}}</a></li>
+{{end}}</ul>
+{{end}}{{if .Removed}}<h3>Removed</h3>
+<ul>
+{{range .Removed}}<li>{{.Title}}</li>
+{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`
+
+// Render writes the report in the given format
+func (r *Report) Render(w io.Writer, format string) error {
+	switch format {
+	case FORMAT_HTML:
+		tmpl := htmltemplate.Must(htmltemplate.New("digest").Funcs(funcs).Parse(HTML_TEMPLATE))
+		return tmpl.Execute(w, r)
+	case FORMAT_MARKDOWN:
+		tmpl := texttemplate.Must(texttemplate.New("digest").Funcs(funcs).Parse(MARKDOWN_TEMPLATE))
+		return tmpl.Execute(w, r)
+	case FORMAT_TEXT:
+		tmpl := texttemplate.Must(texttemplate.New("digest").Funcs(funcs).Parse(TEXT_TEMPLATE))
+		return tmpl.Execute(w, r)
+	}
+	return CheckFormat(format)
+}
+
+// State remembers when the last digest was sent
+type State struct {
+	LastSent time.Time
+}
+
+// LoadState reads the state from dir, a missing file is an empty state
+func LoadState(dir string) (State, error) {
+	state := State{}
+	content, err := os.ReadFile(filepath.Join(dir, STATE_FILE))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(content, &state)
+	return state, err
+}
+
+// SaveState writes the state to dir
+func SaveState(dir string, state State) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, STATE_FILE), content, data.FILE_PERMISSIONS)
+}
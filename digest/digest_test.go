@@ -0,0 +1,88 @@
+package digest_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/digest"
+)
+
+var since = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+var until = since.Add(7 * 24 * time.Hour)
+var during = since.Add(24 * time.Hour)
+
+func testPlaylists() []data.Playlist {
+	return []data.Playlist{
+		{
+			Id:    "PL1",
+			Title: "Lectures",
+			Videos: []data.Video{
+				{Id: "i1", VideoId: "v1", Title: "Watched earlier", PlaylistId: "PL1", Watched: true},
+				{Id: "i2", VideoId: "v2", Title: "Watched now", PlaylistId: "PL1", Watched: true, WatchedAt: during},
+				{Id: "i3", VideoId: "v3", Title: "New", PlaylistId: "PL1", DiscoveredAt: during},
+			},
+			Removed: []data.Video{
+				{Id: "i4", VideoId: "v4", Title: "Gone", PlaylistId: "PL1", RemovedAt: during},
+			},
+		},
+		{
+			Id:     "PL2",
+			Title:  "Unchanged",
+			Videos: []data.Video{{Id: "i5", Title: "Old", PlaylistId: "PL2"}},
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	report := digest.New(testPlaylists(), since, until)
+
+	if len(report.Playlists) != 1 {
+		t.Fatalf("Wanted only the changed playlist, got %+v", report.Playlists)
+	}
+
+	playlist := report.Playlists[0]
+	if len(playlist.New) != 1 || len(playlist.Watched) != 1 || len(playlist.Removed) != 1 {
+		t.Fatalf("Wanted one new, watched and removed video, got %+v", playlist)
+	}
+	if playlist.WatchedBefore != 1 || playlist.TotalBefore != 3 {
+		t.Fatalf("Wanted 1/3 watched before, got %d/%d", playlist.WatchedBefore, playlist.TotalBefore)
+	}
+	if playlist.WatchedAfter != 2 || playlist.TotalAfter != 3 {
+		t.Fatalf("Wanted 2/3 watched after, got %d/%d", playlist.WatchedAfter, playlist.TotalAfter)
+	}
+}
+
+type RenderTest struct {
+	format string
+	want   []string
+}
+
+func TestRender(t *testing.T) {
+	tests := []RenderTest{
+		{digest.FORMAT_MARKDOWN, []string{"## [Lectures]", "- [New](https://youtube.com/watch?v=v3&list=PL1)", "33% → 66%", "- Gone"}},
+		{digest.FORMAT_HTML, []string{`<a href="https://youtube.com/watch?v=v3&amp;list=PL1">New</a>`, "<li>Gone</li>"}},
+		{digest.FORMAT_TEXT, []string{"  + New", "  ✓ Watched now", "  - Gone"}},
+	}
+
+	report := digest.New(testPlaylists(), since, until)
+	for _, test := range tests {
+		buffer := bytes.Buffer{}
+		err := report.Render(&buffer, test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, want := range test.want {
+			if !strings.Contains(buffer.String(), want) {
+				t.Fatalf("%s: Wanted %q in the report, got\n%s", test.format, want, buffer.String())
+			}
+		}
+	}
+
+	if err := report.Render(&bytes.Buffer{}, "pdf"); err == nil {
+		t.Fatal("Wanted an error for an unknown format")
+	}
+}
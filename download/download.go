@@ -0,0 +1,300 @@
+package download
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+// prefixes of the lines tubevault asks yt-dlp to print
+const (
+	PREFIX_PROGRESS = "tubevault-progress "
+	PREFIX_FILE     = "tubevault-file "
+)
+
+// PROGRESS_STEP is the progress in percent between two writes to the vault
+const PROGRESS_STEP = 5
+
+// Queue marks the videos with the given ids of the playlist for download.
+// Without ids every video that is not downloaded yet is queued.
+func Queue(dr data.DataRetriever, playlistId string, ids ...string) ([]data.Video, error) {
+	playlist, err := data.GetPlaylist(dr, playlistId)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := []data.Video{}
+	for idx := range playlist.Videos {
+		video := &playlist.Videos[idx]
+		if len(ids) > 0 && !contains(ids, video.Id) && !contains(ids, video.VideoId) {
+			continue
+		}
+		if video.Download.Status == data.DOWNLOAD_DONE || video.Pending() {
+			continue
+		}
+
+		video.Download = data.Download{Status: data.DOWNLOAD_QUEUED, UpdatedAt: time.Now()}
+		queued = append(queued, *video)
+	}
+
+	return queued, dr.SavePlaylist(playlist)
+}
+
+// Cancel removes the videos with the given ids from the queue
+func Cancel(dr data.DataRetriever, playlistId string, ids ...string) error {
+	playlist, err := data.GetPlaylist(dr, playlistId)
+	if err != nil {
+		return err
+	}
+
+	for idx := range playlist.Videos {
+		video := &playlist.Videos[idx]
+		if contains(ids, video.Id) && video.Download.Status == data.DOWNLOAD_QUEUED {
+			video.Download = data.Download{}
+		}
+	}
+
+	return dr.SavePlaylist(playlist)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Pending returns the videos of the vault that wait for their download
+func Pending(dr data.DataRetriever) ([]data.Video, error) {
+	playlists, err := dr.GetPlaylists()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []data.Video{}
+	for idx := range playlists {
+		for _, video := range playlists[idx].Videos {
+			if video.Pending() {
+				pending = append(pending, video)
+			}
+		}
+	}
+	return pending, nil
+}
+
+// Manager downloads the queued videos with yt-dlp
+type Manager struct {
+	config data.DownloadConfig
+	dr     data.DataRetriever
+	// vaultPath is the vault the downloads are stored in without a Dir
+	vaultPath string
+
+	// mutex serializes the updates of the vault, several downloads of
+	// the same playlist would overwrite each other otherwise
+	mutex sync.Mutex
+}
+
+// New creates a manager for the vault of dr, vaultPath is its path or
+// empty for the default vault
+func New(config data.DownloadConfig, vaultPath string, dr data.DataRetriever) *Manager {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	return &Manager{config: config, dr: dr, vaultPath: vaultPath}
+}
+
+func (m *Manager) update(video *data.Video, download data.Download) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	download.UpdatedAt = time.Now()
+	video.Download = download
+	return m.dr.UpdateVideoDownload(video.PlaylistId, video.Id, download)
+}
+
+// dir returns the directory the videos of the playlist are stored in
+func (m *Manager) dir(playlistId string) (string, error) {
+	dir := m.config.Dir
+	if dir == "" {
+		var err error
+		dir, err = data.GetDownloadDirPath(m.vaultPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	dir = filepath.Join(dir, playlistId)
+	return dir, os.MkdirAll(dir, data.DIR_PERMISSIONS)
+}
+
+// Run downloads the pending videos until the queue is empty or ctx is
+// done. Videos that are queued while it runs are downloaded as well.
+// Failed downloads are recorded in the vault, the returned error is only
+// about the vault itself.
+func (m *Manager) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		pending, err := Pending(m.dr)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		err = m.downloadAll(ctx, pending)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) downloadAll(ctx context.Context, videos []data.Video) error {
+	slots := make(chan struct{}, m.config.Concurrency)
+	errs := make([]error, len(videos))
+	wait := sync.WaitGroup{}
+
+	for idx := range videos {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wait.Add(1)
+		go func(idx int) {
+			defer wait.Done()
+			defer func() { <-slots }()
+			errs[idx] = m.Download(ctx, &videos[idx])
+		}(idx)
+	}
+
+	wait.Wait()
+	return errors.Join(errs...)
+}
+
+// Download downloads a single video and records the progress in the vault
+func (m *Manager) Download(ctx context.Context, video *data.Video) error {
+	err := m.update(video, data.Download{Status: data.DOWNLOAD_DOWNLOADING})
+	if err != nil {
+		return err
+	}
+
+	file, err := m.run(ctx, video)
+	if ctx.Err() != nil {
+		// interrupted downloads are continued by the next run
+		return m.update(video, data.Download{Status: data.DOWNLOAD_QUEUED})
+	}
+	if err != nil {
+		return m.update(video, data.Download{Status: data.DOWNLOAD_FAILED, Error: err.Error()})
+	}
+	return m.update(video, data.Download{Status: data.DOWNLOAD_DONE, Progress: 100, File: file})
+}
+
+// run runs yt-dlp and returns the downloaded file
+func (m *Manager) run(ctx context.Context, video *data.Video) (string, error) {
+	dir, err := m.dir(video.PlaylistId)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{
+		"--newline",
+		"--no-playlist",
+		"--quiet",
+		"--progress",
+		"--progress-template", "download:" + PREFIX_PROGRESS + "%(progress._percent_str)s",
+		"--print", "after_move:" + PREFIX_FILE + "%(filepath)s",
+		"--output", filepath.Join(dir, "%(title)s [%(id)s].%(ext)s"),
+	}
+	if m.config.Format != "" {
+		args = append(args, "--format", m.config.Format)
+	}
+	args = append(args, m.config.Args...)
+	args = append(args, "--", "https://youtube.com/watch?v="+video.VideoId)
+
+	reader, writer := io.Pipe()
+	cmd := exec.CommandContext(ctx, m.config.Command, args...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	err = cmd.Start()
+	if err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.Close()
+		done <- err
+	}()
+
+	file, lastError, err := m.follow(reader, video)
+	if err != nil {
+		// keep reading so yt-dlp is not blocked by a full pipe
+		go io.Copy(io.Discard, reader)
+		<-done
+		return "", err
+	}
+
+	err = <-done
+	if err != nil {
+		if lastError != "" {
+			return "", errors.New(lastError)
+		}
+		return "", fmt.Errorf("%s: %w", m.config.Command, err)
+	}
+	if file == "" {
+		return "", errors.New(m.config.Command + " did not report the downloaded file")
+	}
+	return file, nil
+}
+
+// follow parses the output of yt-dlp, records the progress and returns
+// the file and the last error message
+func (m *Manager) follow(output io.Reader, video *data.Video) (string, string, error) {
+	file := ""
+	lastError := ""
+	saved := 0.0
+
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, PREFIX_PROGRESS):
+			percent := strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(line, PREFIX_PROGRESS)), "%")
+			progress, err := strconv.ParseFloat(percent, 64)
+			if err != nil || progress-saved < PROGRESS_STEP {
+				continue
+			}
+
+			saved = progress
+			err = m.update(video, data.Download{Status: data.DOWNLOAD_DOWNLOADING, Progress: progress})
+			if err != nil {
+				return "", "", err
+			}
+
+		case strings.HasPrefix(line, PREFIX_FILE):
+			file = strings.TrimPrefix(line, PREFIX_FILE)
+
+		case strings.HasPrefix(line, "ERROR:"):
+			lastError = strings.TrimSpace(strings.TrimPrefix(line, "ERROR:"))
+		}
+	}
+
+	return file, lastError, scanner.Err()
+}
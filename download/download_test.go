@@ -0,0 +1,86 @@
+package download_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/download"
+)
+
+// FAKE_YT_DLP behaves like yt-dlp with the arguments of the manager: it
+// fails for the video "broken" and writes a file for every other video
+const FAKE_YT_DLP = `#!/bin/sh
+for arg; do url="$arg"; done
+case "$url" in
+*broken) echo "ERROR: [youtube] broken: Video unavailable" >&2; exit 1 ;;
+esac
+echo "tubevault-progress  10.0%"
+echo "tubevault-progress  55.5%"
+file="$DOWNLOAD_DIR/${url##*=}.mp4"
+echo video > "$file"
+echo "tubevault-file $file"
+`
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	command := filepath.Join(dir, "yt-dlp")
+	err := os.WriteFile(command, []byte(FAKE_YT_DLP), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOWNLOAD_DIR", dir)
+
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dr.SavePlaylist(&data.Playlist{
+		Id: "PL1",
+		Videos: []data.Video{
+			{Id: "i1", VideoId: "good", PlaylistId: "PL1"},
+			{Id: "i2", VideoId: "broken", PlaylistId: "PL1"},
+			{Id: "i3", VideoId: "skipped", PlaylistId: "PL1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queued, err := download.Queue(dr, "PL1", "i1", "i2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("Wanted 2 queued videos, got %d", len(queued))
+	}
+
+	manager := download.New(data.DownloadConfig{Command: command, Concurrency: 2, Dir: t.TempDir()}, "", dr)
+	err = manager.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlist, err := data.GetPlaylist(dr, "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good := playlist.FindVideo("i1")
+	if file, ok := good.LocalFile(); !ok || !strings.HasSuffix(file, "good.mp4") {
+		t.Fatalf("Wanted the downloaded file, got %+v", good.Download)
+	}
+
+	broken := playlist.FindVideo("i2")
+	if broken.Download.Status != data.DOWNLOAD_FAILED || !strings.Contains(broken.Download.Error, "Video unavailable") {
+		t.Fatalf("Wanted the failure to be recorded, got %+v", broken.Download)
+	}
+
+	skipped := playlist.FindVideo("i3")
+	if skipped.Download.Status != data.DOWNLOAD_NONE {
+		t.Fatalf("Wanted the video that was not queued to stay, got %+v", skipped.Download)
+	}
+}
@@ -0,0 +1,187 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+const (
+	ATOM_NAMESPACE = "http://www.w3.org/2005/Atom"
+	CONTENT_TYPE   = "application/atom+xml; charset=utf-8"
+
+	// DEFAULT_LIMIT is the number of entries of a feed
+	DEFAULT_LIMIT = 100
+)
+
+// Feed is an atom feed of newly discovered videos
+type Feed struct {
+	XMLName xml.Name  `xml:"feed"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Id      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated time.Time `xml:"updated"`
+	Links   []Link    `xml:"link"`
+	Author  Person    `xml:"author"`
+	Entries []Entry   `xml:"entry"`
+}
+
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type Person struct {
+	Name string `xml:"name"`
+}
+
+type Category struct {
+	Term string `xml:"term,attr"`
+}
+
+type Text struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Entry is a single video of a feed
+type Entry struct {
+	Id         string     `xml:"id"`
+	Title      string     `xml:"title"`
+	Updated    time.Time  `xml:"updated"`
+	Published  time.Time  `xml:"published"`
+	Links      []Link     `xml:"link"`
+	Author     Person     `xml:"author"`
+	Categories []Category `xml:"category"`
+	Summary    Text       `xml:"summary"`
+}
+
+// Options configure a feed
+type Options struct {
+	// Title of the feed
+	Title string
+	// Id identifies the feed, e.g. "vault" or "playlist/<id>"
+	Id string
+	// Self is the url the feed is available at, if any
+	Self string
+	// Limit is the maximum number of entries, DEFAULT_LIMIT if 0
+	Limit int
+}
+
+// New builds a feed of the videos that were discovered by a refresh in
+// the given playlists, newest first
+func New(playlists []data.Playlist, options Options) *Feed {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = DEFAULT_LIMIT
+	}
+
+	feed := &Feed{
+		Xmlns:   ATOM_NAMESPACE,
+		Id:      "urn:" + data.APP_NAME + ":feed:" + url.PathEscape(options.Id),
+		Title:   options.Title,
+		Author:  Person{data.APP_NAME},
+		Entries: []Entry{},
+	}
+	if options.Self != "" {
+		feed.Links = append(feed.Links, Link{Href: options.Self, Rel: "self", Type: CONTENT_TYPE})
+	}
+
+	for idx := range playlists {
+		playlist := &playlists[idx]
+		categories := []Category{{playlist.Title}}
+		for _, tag := range playlist.Tags {
+			categories = append(categories, Category{tag})
+		}
+
+		for vidx := range playlist.Videos {
+			video := &playlist.Videos[vidx]
+			if video.DiscoveredAt.IsZero() {
+				continue
+			}
+
+			feed.Entries = append(feed.Entries, Entry{
+				Id:         "urn:" + data.APP_NAME + ":video:" + url.PathEscape(playlist.Id) + ":" + url.PathEscape(video.Id),
+				Title:      video.Title,
+				Updated:    video.DiscoveredAt.UTC(),
+				Published:  video.PublishedAt.UTC(),
+				Links:      []Link{{Href: video.URL(), Rel: "alternate", Type: "text/html"}},
+				Author:     Person{playlist.Title},
+				Categories: categories,
+				Summary:    Text{"text", video.Description},
+			})
+		}
+	}
+
+	sort.SliceStable(feed.Entries, func(i, j int) bool {
+		return feed.Entries[i].Updated.After(feed.Entries[j].Updated)
+	})
+	if len(feed.Entries) > limit {
+		feed.Entries = feed.Entries[:limit]
+	}
+
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	} else {
+		feed.Updated = time.Unix(0, 0).UTC()
+	}
+
+	return feed
+}
+
+// ForVault builds the feed of every tracked playlist
+func ForVault(playlists []data.Playlist, options Options) *Feed {
+	if options.Title == "" {
+		options.Title = "tubevault: new videos"
+	}
+	options.Id = "vault"
+	return New(playlists, options)
+}
+
+// ForPlaylist builds the feed of a single playlist
+func ForPlaylist(playlist *data.Playlist, options Options) *Feed {
+	if options.Title == "" {
+		options.Title = "tubevault: " + playlist.Title
+	}
+	options.Id = "playlist:" + playlist.Id
+	return New([]data.Playlist{*playlist}, options)
+}
+
+// ForTag builds the feed of the playlists tagged with tag
+func ForTag(playlists []data.Playlist, tag string, options Options) *Feed {
+	tagged := []data.Playlist{}
+	for idx := range playlists {
+		if playlists[idx].HasTag(tag) {
+			tagged = append(tagged, playlists[idx])
+		}
+	}
+
+	if options.Title == "" {
+		options.Title = "tubevault: " + tag
+	}
+	options.Id = "tag:" + tag
+	return New(tagged, options)
+}
+
+// Write writes the feed as xml
+func (f *Feed) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(f)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,80 @@
+package feed_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/feed"
+)
+
+var playlists = []data.Playlist{
+	{
+		Id:    "PL1",
+		Title: "Lectures",
+		Tags:  []string{"uni"},
+		Videos: []data.Video{
+			{Id: "i1", VideoId: "v1", Title: "Initial", PlaylistId: "PL1"},
+			{Id: "i2", VideoId: "v2", Title: "Older", PlaylistId: "PL1", DiscoveredAt: time.Unix(100, 0)},
+		},
+	},
+	{
+		Id:    "PL2",
+		Title: "Talks",
+		Videos: []data.Video{
+			{Id: "i3", VideoId: "v3", Title: "Newer", PlaylistId: "PL2", DiscoveredAt: time.Unix(200, 0)},
+		},
+	},
+}
+
+type FeedTest struct {
+	name   string
+	feed   *feed.Feed
+	titles []string
+}
+
+func TestFeed(t *testing.T) {
+	tests := []FeedTest{
+		{"vault", feed.ForVault(playlists, feed.Options{}), []string{"Newer", "Older"}},
+		{"playlist", feed.ForPlaylist(&playlists[1], feed.Options{}), []string{"Newer"}},
+		{"tag", feed.ForTag(playlists, "uni", feed.Options{}), []string{"Older"}},
+		{"limit", feed.ForVault(playlists, feed.Options{Limit: 1}), []string{"Newer"}},
+	}
+
+	for _, test := range tests {
+		titles := []string{}
+		for _, entry := range test.feed.Entries {
+			titles = append(titles, entry.Title)
+		}
+		if len(titles) != len(test.titles) {
+			t.Fatalf("%s: Wanted %v, got %v", test.name, test.titles, titles)
+		}
+		for idx := range titles {
+			if titles[idx] != test.titles[idx] {
+				t.Fatalf("%s: Wanted %v, got %v", test.name, test.titles, titles)
+			}
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := feed.ForVault(playlists, feed.Options{Self: "http://localhost/feed"}).Write(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := feed.Feed{}
+	err = xml.Unmarshal(buffer.Bytes(), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Updated.Equal(time.Unix(200, 0)) {
+		t.Fatalf("Wanted the feed to be updated with the newest entry, got %v", parsed.Updated)
+	}
+	if parsed.Entries[0].Links[0].Href != "https://youtube.com/watch?v=v3&list=PL2" {
+		t.Fatalf("Wanted the video url as link, got %+v", parsed.Entries[0].Links)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"os"
+
 	"github.com/baumple/watchvault/cli"
 	_ "github.com/lib/pq"
 )
 
 func main() {
-        cli.StartCLI()
+	cli.StartCLI(os.Args[1:])
 }
@@ -0,0 +1,119 @@
+package mpv
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+// CONNECT_TIMEOUT is how long mpv has to create its ipc socket
+const CONNECT_TIMEOUT = 10 * time.Second
+
+// Player plays videos in mpv and tracks their progress
+type Player struct {
+	config data.MpvConfig
+	dr     data.DataRetriever
+}
+
+func New(config data.MpvConfig, dr data.DataRetriever) *Player {
+	return &Player{config, dr}
+}
+
+// Queue returns the video at index and the unwatched videos after it
+func Queue(videos []data.Video, index int) []data.Video {
+	queue := []data.Video{videos[index]}
+	for _, video := range videos[index+1:] {
+		if !video.Watched {
+			queue = append(queue, video)
+		}
+	}
+	return queue
+}
+
+// connect waits until mpv listens on socket
+func connect(socket string, exited <-chan error) (net.Conn, error) {
+	deadline := time.Now().Add(CONNECT_TIMEOUT)
+	for {
+		conn, err := net.Dial("unix", socket)
+		if err == nil {
+			return conn, nil
+		}
+
+		select {
+		case err := <-exited:
+			return nil, fmt.Errorf("mpv exited before playback: %w", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("could not connect to mpv: %w", err)
+		}
+	}
+}
+
+// Play plays videos one after another and blocks until mpv exits. The
+// progress is written to the vault while the videos play. It returns the
+// videos with their new state.
+func (p *Player) Play(videos []data.Video) ([]data.Video, error) {
+	if len(videos) == 0 {
+		return videos, errors.New("nothing to play")
+	}
+	for idx := range videos {
+		// videos stored before the video ids were recorded
+		if _, ok := videos[idx].LocalFile(); !ok && videos[idx].VideoId == "" {
+			return videos, fmt.Errorf("%q has no video id, refresh the playlist first", videos[idx].Title)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", data.APP_NAME+"-mpv-*")
+	if err != nil {
+		return videos, err
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "mpv.sock")
+
+	args := append([]string{"--input-ipc-server=" + socket, "--no-terminal"}, p.config.Args...)
+	args = append(args, "--")
+	for idx := range videos {
+		if file, ok := videos[idx].LocalFile(); ok {
+			args = append(args, file)
+			continue
+		}
+		// without the playlist in the url, otherwise mpv would load the
+		// whole playlist for every video
+		args = append(args, "https://youtube.com/watch?v="+videos[idx].VideoId)
+	}
+
+	cmd := exec.Command(p.config.Command, args...)
+	err = cmd.Start()
+	if err != nil {
+		return videos, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	conn, err := connect(socket, exited)
+	if err != nil {
+		cmd.Process.Kill()
+		return videos, err
+	}
+	defer conn.Close()
+
+	session := NewSession(conn, p.dr, append([]data.Video{}, videos...), p.config.WatchedThreshold)
+	err = session.Run()
+
+	exitErr := <-exited
+	if err == nil && exitErr != nil {
+		err = fmt.Errorf("mpv: %w", exitErr)
+	}
+	return session.Videos(), err
+}
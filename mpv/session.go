@@ -0,0 +1,246 @@
+package mpv
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+// SAVE_INTERVAL is how much playback passes between two saves of the
+// position, so a crash of mpv loses at most this much
+const SAVE_INTERVAL = 15 * time.Second
+
+// ids of the observed properties
+const (
+	PROPERTY_TIME_POS = iota + 1
+	PROPERTY_DURATION
+	PROPERTY_PLAYLIST_POS
+)
+
+// command is a request to mpv
+type command struct {
+	Command []any `json:"command"`
+}
+
+// event is a message from mpv, either an event or the reply to a command
+type event struct {
+	Event  string          `json:"event"`
+	Id     int             `json:"id"`
+	Name   string          `json:"name"`
+	Data   json.RawMessage `json:"data"`
+	Reason string          `json:"reason"`
+}
+
+// Session follows the playback of a queue of videos over the json ipc
+// protocol of mpv and writes the progress back to the vault
+type Session struct {
+	conn      io.ReadWriter
+	dr        data.DataRetriever
+	videos    []data.Video
+	threshold float64
+
+	// current is the index of the playing video in videos, -1 before
+	// the first file was loaded
+	current  int
+	position time.Duration
+	duration time.Duration
+	// saved is the position that was written last
+	saved time.Duration
+	// finished is set when the current video played to its end
+	finished bool
+}
+
+// NewSession creates a session for the videos that are loaded in mpv in
+// the same order. A video is marked as watched once threshold (0 to 1)
+// of it was played.
+func NewSession(conn io.ReadWriter, dr data.DataRetriever, videos []data.Video, threshold float64) *Session {
+	return &Session{
+		conn:      conn,
+		dr:        dr,
+		videos:    videos,
+		threshold: threshold,
+		current:   -1,
+	}
+}
+
+// Videos returns the queued videos with their updated watched state and
+// position
+func (s *Session) Videos() []data.Video {
+	return s.videos
+}
+
+func (s *Session) send(args ...any) error {
+	line, err := json.Marshal(command{args})
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(append(line, '\n'))
+	return err
+}
+
+// Run follows the playback until mpv shuts down or closes the connection
+func (s *Session) Run() error {
+	err := errors.Join(
+		s.send("observe_property", PROPERTY_TIME_POS, "time-pos"),
+		s.send("observe_property", PROPERTY_DURATION, "duration"),
+		s.send("observe_property", PROPERTY_PLAYLIST_POS, "playlist-pos"),
+	)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(s.conn)
+	for scanner.Scan() {
+		e := event{}
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Event == "" {
+			// replies to commands
+			continue
+		}
+
+		err = s.handle(e)
+		if err != nil {
+			s.save()
+			return err
+		}
+		if e.Event == "shutdown" {
+			break
+		}
+	}
+
+	// closing the socket when mpv quits is no error
+	return errors.Join(s.save(), ignoreClosed(scanner.Err()))
+}
+
+func ignoreClosed(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+		return nil
+	}
+	return err
+}
+
+// seconds parses a property value in seconds, null is 0
+func seconds(raw json.RawMessage) time.Duration {
+	var value float64
+	json.Unmarshal(raw, &value)
+	return time.Duration(value * float64(time.Second))
+}
+
+func (s *Session) handle(e event) error {
+	switch e.Event {
+	case "property-change":
+		switch e.Id {
+		case PROPERTY_TIME_POS:
+			return s.progress(seconds(e.Data))
+		case PROPERTY_DURATION:
+			s.duration = seconds(e.Data)
+		case PROPERTY_PLAYLIST_POS:
+			index := -1
+			json.Unmarshal(e.Data, &index)
+			return s.switchTo(index)
+		}
+
+	case "file-loaded":
+		// resume where the video was stopped the last time
+		if s.current >= 0 && s.videos[s.current].Position.Duration > 0 {
+			return s.send("seek", s.videos[s.current].Position.Seconds(), "absolute")
+		}
+
+	case "end-file":
+		if e.Reason == "eof" && s.current >= 0 {
+			s.finished = true
+			return s.markWatched()
+		}
+	}
+	return nil
+}
+
+// switchTo saves the progress of the current video and follows the
+// video at index
+func (s *Session) switchTo(index int) error {
+	if index == s.current {
+		return nil
+	}
+
+	err := s.save()
+	s.current = -1
+	if index >= 0 && index < len(s.videos) {
+		s.current = index
+	}
+	s.position = 0
+	s.duration = 0
+	s.finished = false
+	s.saved = s.currentPosition()
+	return err
+}
+
+func (s *Session) currentPosition() time.Duration {
+	if s.current < 0 {
+		return 0
+	}
+	return s.videos[s.current].Position.Duration
+}
+
+// progress records the playback position and marks the video as watched
+// once the threshold is reached
+func (s *Session) progress(position time.Duration) error {
+	if s.current < 0 || position <= 0 {
+		return nil
+	}
+	s.position = position
+
+	if s.duration > 0 && float64(position) >= s.threshold*float64(s.duration) {
+		err := s.markWatched()
+		if err != nil {
+			return err
+		}
+	}
+
+	if position-s.saved >= SAVE_INTERVAL || s.saved-position >= SAVE_INTERVAL {
+		return s.save()
+	}
+	return nil
+}
+
+func (s *Session) markWatched() error {
+	video := &s.videos[s.current]
+	if video.Watched {
+		return nil
+	}
+
+	err := s.dr.UpdateVideoWatched(video.PlaylistId, video.Id, true)
+	if err != nil {
+		return err
+	}
+	video.SetWatched(true)
+	return nil
+}
+
+// save writes the position of the current video. A video that played to
+// its end starts from the beginning again.
+func (s *Session) save() error {
+	if s.current < 0 {
+		return nil
+	}
+
+	position := s.position
+	if s.finished {
+		position = 0
+	}
+
+	video := &s.videos[s.current]
+	if position == video.Position.Duration {
+		return nil
+	}
+
+	err := s.dr.UpdateVideoPosition(video.PlaylistId, video.Id, position)
+	if err != nil {
+		return err
+	}
+	video.Position = data.Duration{Duration: position}
+	s.saved = position
+	return nil
+}
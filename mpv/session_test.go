@@ -0,0 +1,151 @@
+package mpv_test
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/mpv"
+)
+
+// fakeMpv accepts a single connection on socket, writes events to it and
+// sends the received commands to commands
+func fakeMpv(t *testing.T, socket string, events []string, commands chan<- string) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		go func() {
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				commands <- scanner.Text()
+			}
+			close(commands)
+		}()
+
+		for _, event := range events {
+			conn.Write([]byte(event + "\n"))
+			// give the session time to handle the event like with a
+			// real player
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+}
+
+func TestSession(t *testing.T) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	playlist := data.Playlist{
+		Id: "PL1",
+		Videos: []data.Video{
+			{Id: "i1", VideoId: "v1", PlaylistId: "PL1", Position: data.Duration{Duration: 30 * time.Second}},
+			{Id: "i2", VideoId: "v2", PlaylistId: "PL1"},
+		},
+	}
+	err = dr.SavePlaylist(&playlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "mpv.sock")
+	commands := make(chan string, 16)
+	fakeMpv(t, socket, []string{
+		`{"request_id":0,"error":"success"}`,
+		`{"event":"property-change","id":3,"name":"playlist-pos","data":0}`,
+		`{"event":"file-loaded"}`,
+		`{"event":"property-change","id":2,"name":"duration","data":100.0}`,
+		`{"event":"property-change","id":1,"name":"time-pos","data":50.0}`,
+		`{"event":"property-change","id":1,"name":"time-pos","data":95.5}`,
+		`{"event":"end-file","reason":"eof"}`,
+		`{"event":"property-change","id":3,"name":"playlist-pos","data":1}`,
+		`{"event":"property-change","id":2,"name":"duration","data":200.0}`,
+		`{"event":"property-change","id":1,"name":"time-pos","data":40.0}`,
+		`{"event":"end-file","reason":"quit"}`,
+		`{"event":"shutdown"}`,
+	}, commands)
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	session := mpv.NewSession(conn, dr, playlist.Videos, 0.9)
+	err = session.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	sent := []string{}
+	for command := range commands {
+		sent = append(sent, command)
+	}
+	if !strings.Contains(strings.Join(sent, "\n"), `"seek",30,"absolute"`) {
+		t.Fatalf("Wanted a seek to the stored position, got %v", sent)
+	}
+
+	stored, err := data.GetPlaylist(dr, "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := stored.FindVideo("i1")
+	if !first.Watched || first.WatchedAt.IsZero() || first.Position.Duration != 0 {
+		t.Fatalf("Wanted the finished video to be watched without position, got %+v", first)
+	}
+
+	second := stored.FindVideo("i2")
+	if second.Watched || second.Position.Duration != 40*time.Second {
+		t.Fatalf("Wanted the stopped video to keep its position, got %+v", second)
+	}
+}
+
+type QueueTest struct {
+	index int
+	want  []string
+}
+
+func TestQueue(t *testing.T) {
+	videos := []data.Video{{Id: "a"}, {Id: "b", Watched: true}, {Id: "c"}, {Id: "d"}}
+	tests := []QueueTest{
+		{0, []string{"a", "c", "d"}},
+		{1, []string{"b", "c", "d"}},
+		{3, []string{"d"}},
+	}
+
+	for _, test := range tests {
+		ids := []string{}
+		for _, video := range mpv.Queue(videos, test.index) {
+			ids = append(ids, video.Id)
+		}
+		if strings.Join(ids, ",") != strings.Join(test.want, ",") {
+			t.Fatalf("Wanted queue %v from %d, got %v", test.want, test.index, ids)
+		}
+	}
+}
+
+func TestPlayWithoutVideoId(t *testing.T) {
+	// the command must not be started
+	player := mpv.New(data.MpvConfig{Command: "/nonexistent/mpv"}, nil)
+	_, err := player.Play([]data.Video{{Id: "i1", Title: "Old"}})
+	if err == nil || !strings.Contains(err.Error(), "refresh the playlist") {
+		t.Fatalf("Wanted an error asking for a refresh, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/baumple/watchvault/data"
+)
+
+const (
+	TYPE_DESKTOP = "notify-send"
+	TYPE_SMTP    = "smtp"
+	TYPE_NTFY    = "ntfy"
+	TYPE_WEBHOOK = "webhook"
+
+	DEFAULT_TITLE = `{{.Playlist.Title}}: {{len .Videos}} new video{{if ne (len .Videos) 1}}s{{end}}`
+	DEFAULT_BODY  = `{{range .Videos}}* {{.Title}}
+  {{.URL}}
+{{end}}`
+)
+
+// Event describes the new videos a refresh found in a playlist
+type Event struct {
+	Playlist *data.Playlist
+	Videos   []data.Video
+}
+
+// Message is an event rendered with the templates of a sink
+type Message struct {
+	Title string
+	Body  string
+}
+
+// Sink delivers notifications to a single destination
+type Sink interface {
+	Send(event Event, message Message) error
+}
+
+// route is a sink together with the playlists it is notified about
+type route struct {
+	sink      Sink
+	kind      string
+	playlists []string
+	title     *template.Template
+	body      *template.Template
+}
+
+// Notifier dispatches events to every sink that is routed to the
+// playlist of the event
+type Notifier struct {
+	routes []route
+}
+
+// New creates a Notifier for the configured sinks
+func New(configs []data.NotificationConfig) (*Notifier, error) {
+	notifier := &Notifier{}
+	for idx, config := range configs {
+		sink, err := newSink(config)
+		if err != nil {
+			return nil, fmt.Errorf("notification %d: %w", idx+1, err)
+		}
+
+		err = notifier.Add(sink, config)
+		if err != nil {
+			return nil, fmt.Errorf("notification %d: %w", idx+1, err)
+		}
+	}
+
+	return notifier, nil
+}
+
+// Add routes events to sink using the playlists and templates of config
+func (n *Notifier) Add(sink Sink, config data.NotificationConfig) error {
+	titleText := config.Title
+	if titleText == "" {
+		titleText = DEFAULT_TITLE
+	}
+	bodyText := config.Body
+	if bodyText == "" {
+		bodyText = DEFAULT_BODY
+	}
+
+	title, err := template.New("title").Parse(titleText)
+	if err != nil {
+		return fmt.Errorf("invalid title template: %w", err)
+	}
+	body, err := template.New("body").Parse(bodyText)
+	if err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+
+	n.routes = append(n.routes, route{
+		sink:      sink,
+		kind:      config.Type,
+		playlists: config.Playlists,
+		title:     title,
+		body:      body,
+	})
+	return nil
+}
+
+func newSink(config data.NotificationConfig) (Sink, error) {
+	switch config.Type {
+	case TYPE_DESKTOP:
+		return newDesktopSink(config), nil
+	case TYPE_SMTP:
+		return newSmtpSink(config)
+	case TYPE_NTFY:
+		return newNtfySink(config)
+	case TYPE_WEBHOOK:
+		return newWebhookSink(config)
+	default:
+		return nil, fmt.Errorf("unknown notification type %q, supported types: %s",
+			config.Type, strings.Join([]string{TYPE_DESKTOP, TYPE_SMTP, TYPE_NTFY, TYPE_WEBHOOK}, ", "))
+	}
+}
+
+// matches returns whether the route is notified about the playlist
+func (r *route) matches(playlistId string) bool {
+	if len(r.playlists) == 0 {
+		return true
+	}
+	for _, id := range r.playlists {
+		if id == playlistId {
+			return true
+		}
+	}
+	return false
+}
+
+// render executes the templates of the route with event
+func (r *route) render(event Event) (Message, error) {
+	title := bytes.Buffer{}
+	err := r.title.Execute(&title, event)
+	if err != nil {
+		return Message{}, err
+	}
+
+	body := bytes.Buffer{}
+	err = r.body.Execute(&body, event)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Title: strings.TrimSpace(title.String()),
+		Body:  strings.TrimSpace(body.String()),
+	}, nil
+}
+
+// Notify sends the new videos of playlist to every matching sink.
+// It does nothing if there are no new videos. A failing sink does not
+// stop the other sinks, all errors are returned together.
+func (n *Notifier) Notify(playlist *data.Playlist, videos []data.Video) error {
+	if n == nil || len(videos) == 0 {
+		return nil
+	}
+
+	event := Event{
+		Playlist: playlist,
+		Videos:   videos,
+	}
+
+	errs := []error{}
+	for idx := range n.routes {
+		route := &n.routes[idx]
+		if !route.matches(playlist.Id) {
+			continue
+		}
+
+		message, err := route.render(event)
+		if err == nil {
+			err = route.sink.Send(event, message)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s notification: %w", route.kind, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
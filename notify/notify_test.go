@@ -0,0 +1,256 @@
+package notify_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/notify"
+)
+
+var playlist = data.Playlist{Id: "PL1", Title: "Lectures"}
+var newVideos = []data.Video{
+	{Id: "i1", VideoId: "v1", Title: "Lecture 1", PlaylistId: "PL1"},
+	{Id: "i2", VideoId: "v2", Title: "Lecture 2", PlaylistId: "PL1"},
+}
+
+func TestWebhook(t *testing.T) {
+	var payload notify.WebhookPayload
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	notifier, err := notify.New([]data.NotificationConfig{
+		{Type: notify.TYPE_WEBHOOK, Url: server.URL, Token: "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Notify(&playlist, newVideos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if auth != "Bearer secret" {
+		t.Fatalf("Wanted the token to be sent, got %q", auth)
+	}
+	if payload.Playlist.Id != "PL1" || len(payload.Videos) != 2 {
+		t.Fatalf("Wanted the playlist and both videos, got %+v", payload)
+	}
+	if payload.Title != "Lectures: 2 new videos" {
+		t.Fatalf("Wanted the default title, got %q", payload.Title)
+	}
+	if !strings.Contains(payload.Message, "https://youtube.com/watch?v=v1&list=PL1") {
+		t.Fatalf("Wanted the video urls in the message, got %q", payload.Message)
+	}
+}
+
+func TestNtfyRoutingAndTemplates(t *testing.T) {
+	requests := []*http.Request{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	notifier, err := notify.New([]data.NotificationConfig{
+		{
+			Type:      notify.TYPE_NTFY,
+			Url:       server.URL + "/lectures",
+			Playlists: []string{"PL1"},
+			Title:     "New in {{.Playlist.Title}}",
+			Body:      "{{range .Videos}}{{.Title}};{{end}}",
+			Priority:  "4",
+		},
+		{
+			Type:      notify.TYPE_NTFY,
+			Url:       server.URL + "/other",
+			Playlists: []string{"PL2"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Notify(&playlist, newVideos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Wanted only the routed sink to be notified, got %d requests", len(requests))
+	}
+	if requests[0].URL.Path != "/lectures" {
+		t.Fatalf("Wanted the lectures topic, got %s", requests[0].URL.Path)
+	}
+	if requests[0].Header.Get("Title") != "New in Lectures" || requests[0].Header.Get("Priority") != "4" {
+		t.Fatalf("Wanted the templated title and priority, got %v", requests[0].Header)
+	}
+	if bodies[0] != "Lecture 1;Lecture 2;" {
+		t.Fatalf("Wanted the templated body, got %q", bodies[0])
+	}
+}
+
+func TestNoNewVideos(t *testing.T) {
+	notifier, err := notify.New([]data.NotificationConfig{
+		{Type: notify.TYPE_WEBHOOK, Url: "http://127.0.0.1:1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Notify(&playlist, []data.Video{})
+	if err != nil {
+		t.Fatalf("Wanted nothing to be sent, got %v", err)
+	}
+}
+
+func TestFailingSink(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "topic not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	notifier, err := notify.New([]data.NotificationConfig{
+		{Type: notify.TYPE_NTFY, Url: server.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Notify(&playlist, newVideos)
+	if err == nil || !strings.Contains(err.Error(), "topic not found") {
+		t.Fatalf("Wanted the server error, got %v", err)
+	}
+}
+
+// fakeSmtpServer accepts a single mail and sends its data to mails
+func fakeSmtpServer(t *testing.T, listener net.Listener, mails chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	write := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+
+	write("220 localhost ESMTP")
+	mail := strings.Builder{}
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				mails <- mail.String()
+				write("250 OK")
+				continue
+			}
+			mail.WriteString(line)
+			continue
+		}
+
+		switch command := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			write("250 localhost")
+		case command == "DATA":
+			inData = true
+			write("354 go ahead")
+		case command == "QUIT":
+			write("221 bye")
+			return
+		default:
+			write("250 OK")
+		}
+	}
+}
+
+func TestSmtp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	mails := make(chan string, 1)
+	go fakeSmtpServer(t, listener, mails)
+
+	port, _ := strconv.Atoi(strings.Split(listener.Addr().String(), ":")[1])
+	notifier, err := notify.New([]data.NotificationConfig{
+		{
+			Type: notify.TYPE_SMTP,
+			Host: "127.0.0.1",
+			Port: port,
+			From: "vault@example.com",
+			To:   []string{"me@example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Notify(&playlist, newVideos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-mails
+	if !strings.Contains(mail, "Subject: Lectures: 2 new videos") {
+		t.Fatalf("Wanted the subject in the mail, got %q", mail)
+	}
+	if !strings.Contains(mail, "* Lecture 2") {
+		t.Fatalf("Wanted the videos in the mail, got %q", mail)
+	}
+}
+
+func TestSmtpEncodedSubject(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	mails := make(chan string, 1)
+	go fakeSmtpServer(t, listener, mails)
+
+	err = notify.SendMail(listener.Addr().String(), nil, "vault@example.com", []string{"me@example.com"},
+		"Übungen: 1 new video", "body", "text/plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mail := <-mails
+	if !strings.Contains(mail, "Subject: =?utf-8?q?=C3=9Cbungen:_1_new_video?=") {
+		t.Fatalf("Wanted the subject to be encoded, got %q", mail)
+	}
+}
+
+func TestUnknownType(t *testing.T) {
+	_, err := notify.New([]data.NotificationConfig{{Type: "pigeon"}})
+	if err == nil {
+		t.Fatal("Wanted an error for an unknown notification type")
+	}
+}
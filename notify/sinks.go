@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+const HTTP_TIMEOUT = 15 * time.Second
+
+// desktopSink shows a desktop notification with notify-send
+type desktopSink struct {
+	command string
+	urgency string
+}
+
+func newDesktopSink(config data.NotificationConfig) *desktopSink {
+	command := config.Command
+	if command == "" {
+		command = "notify-send"
+	}
+	return &desktopSink{
+		command: command,
+		urgency: config.Priority,
+	}
+}
+
+func (d *desktopSink) Send(event Event, message Message) error {
+	args := []string{"--app-name", data.APP_NAME}
+	if d.urgency != "" {
+		args = append(args, "--urgency", d.urgency)
+	}
+	args = append(args, message.Title, message.Body)
+
+	output, err := exec.Command(d.command, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", d.command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// smtpSink sends an email
+type smtpSink struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+func newSmtpSink(config data.NotificationConfig) (*smtpSink, error) {
+	if config.Host == "" || config.From == "" || len(config.To) == 0 {
+		return nil, errors.New("smtp needs Host, From and To")
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	return &smtpSink{
+		addr: net.JoinHostPort(config.Host, strconv.Itoa(port)),
+		auth: auth,
+		from: config.From,
+		to:   config.To,
+	}, nil
+}
+
+func (s *smtpSink) Send(event Event, message Message) error {
+	return SendMail(s.addr, s.auth, s.from, s.to, message.Title, message.Body, "text/plain")
+}
+
+// SendMail sends an email with the given subject and body through the smtp
+// server at addr
+func SendMail(addr string, auth smtp.Auth, from string, to []string, subject string, body string, contentType string) error {
+	mail := bytes.Buffer{}
+	fmt.Fprintf(&mail, "From: %s\r\n", from)
+	fmt.Fprintf(&mail, "To: %s\r\n", strings.Join(to, ", "))
+	// headers must be ascii, titles often are not
+	fmt.Fprintf(&mail, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&mail, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&mail, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&mail, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	fmt.Fprintf(&mail, "\r\n")
+	mail.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	mail.WriteString("\r\n")
+
+	return smtp.SendMail(addr, auth, from, to, mail.Bytes())
+}
+
+// ntfySink publishes a message to a ntfy topic
+type ntfySink struct {
+	url      string
+	token    string
+	priority string
+	client   *http.Client
+}
+
+func newNtfySink(config data.NotificationConfig) (*ntfySink, error) {
+	if config.Url == "" {
+		return nil, errors.New("ntfy needs the Url of the topic")
+	}
+	return &ntfySink{
+		url:      config.Url,
+		token:    config.Token,
+		priority: config.Priority,
+		client:   &http.Client{Timeout: HTTP_TIMEOUT},
+	}, nil
+}
+
+func (n *ntfySink) Send(event Event, message Message) error {
+	req, err := http.NewRequest(http.MethodPost, n.url, strings.NewReader(message.Body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Title", message.Title)
+	req.Header.Set("Click", event.Playlist.URL())
+	req.Header.Set("Tags", "tv")
+	if n.priority != "" {
+		req.Header.Set("Priority", n.priority)
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	return doRequest(n.client, req)
+}
+
+// webhookSink posts the event as json
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newWebhookSink(config data.NotificationConfig) (*webhookSink, error) {
+	if config.Url == "" {
+		return nil, errors.New("webhook needs a Url")
+	}
+	headers := map[string]string{}
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+	if config.Token != "" {
+		headers["Authorization"] = "Bearer " + config.Token
+	}
+
+	return &webhookSink{
+		url:     config.Url,
+		headers: headers,
+		client:  &http.Client{Timeout: HTTP_TIMEOUT},
+	}, nil
+}
+
+// WebhookPayload is the json body posted by the webhook sink
+type WebhookPayload struct {
+	Title    string          `json:"title"`
+	Message  string          `json:"message"`
+	Playlist WebhookPlaylist `json:"playlist"`
+	Videos   []WebhookVideo  `json:"videos"`
+}
+
+type WebhookPlaylist struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Url   string `json:"url"`
+}
+
+type WebhookVideo struct {
+	Id          string    `json:"id"`
+	VideoId     string    `json:"video_id"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+func (w *webhookSink) Send(event Event, message Message) error {
+	payload := WebhookPayload{
+		Title:   message.Title,
+		Message: message.Body,
+		Playlist: WebhookPlaylist{
+			Id:    event.Playlist.Id,
+			Title: event.Playlist.Title,
+			Url:   event.Playlist.URL(),
+		},
+		Videos: []WebhookVideo{},
+	}
+	for idx := range event.Videos {
+		video := &event.Videos[idx]
+		payload.Videos = append(payload.Videos, WebhookVideo{
+			Id:          video.Id,
+			VideoId:     video.VideoId,
+			Title:       video.Title,
+			Url:         video.URL(),
+			PublishedAt: video.PublishedAt,
+		})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.headers {
+		req.Header.Set(key, value)
+	}
+
+	return doRequest(w.client, req)
+}
+
+// doRequest sends req and fails on every status code that is not 2xx
+func doRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s", req.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Mail sends an email through the smtp server of config, e.g. the digest
+func Mail(config data.NotificationConfig, subject string, body string, contentType string) error {
+	sink, err := newSmtpSink(config)
+	if err != nil {
+		return err
+	}
+	return SendMail(sink.addr, sink.auth, sink.from, sink.to, subject, body, contentType)
+}
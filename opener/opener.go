@@ -0,0 +1,147 @@
+package opener
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+// placeholders of an opener command
+const (
+	PLACEHOLDER_URL      = "{url}"
+	PLACEHOLDER_VIDEO_ID = "{videoId}"
+	PLACEHOLDER_POSITION = "{position}"
+)
+
+// Target is what an opener opens
+type Target struct {
+	Url     string
+	VideoId string
+	// Position is where playback should start
+	Position time.Duration
+}
+
+// ForPlaylist returns the target of the playlist page
+func ForPlaylist(playlist *data.Playlist) Target {
+	return Target{Url: playlist.URL()}
+}
+
+// ForVideo returns the target of the video, the downloaded file if there
+// is one
+func ForVideo(video *data.Video) Target {
+	target := Target{Url: video.URL(), VideoId: video.VideoId, Position: video.Position.Duration}
+	if file, ok := video.LocalFile(); ok {
+		target.Url = file
+	}
+	return target
+}
+
+// ForChapter returns the target of the video that starts at the chapter
+func ForChapter(video *data.Video, chapter data.Chapter) Target {
+	target := ForVideo(video)
+	target.Position = chapter.Start.Duration
+	if _, ok := video.LocalFile(); !ok {
+		target.Url = fmt.Sprintf("%s&t=%ds", video.URL(), int(chapter.Start.Seconds()))
+	}
+	return target
+}
+
+// Resolve returns the opener of the playlist, which overrides the
+// configured default
+func Resolve(defaultOpener string, playlist *data.Playlist) string {
+	if playlist != nil && playlist.Opener != "" {
+		return playlist.Opener
+	}
+	return defaultOpener
+}
+
+// split splits a command into its arguments. Arguments can be quoted with
+// single or double quotes to contain spaces.
+func split(command string) ([]string, error) {
+	args := []string{}
+	current := strings.Builder{}
+	inArg := false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in opener %q", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// Command returns the arguments of the opener for target. The opener is
+// either a program (e.g. "xdg-open" or "mpv") that gets the url as its
+// last argument, or a command template containing placeholders, e.g.
+// "mpv --start={position} {url}". The command is not run by a shell.
+func Command(opener string, target Target) ([]string, error) {
+	args, err := split(opener)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no opener configured")
+	}
+
+	if !strings.Contains(opener, PLACEHOLDER_URL) &&
+		!strings.Contains(opener, PLACEHOLDER_VIDEO_ID) {
+		args = append(args, PLACEHOLDER_URL)
+	}
+
+	replacer := strings.NewReplacer(
+		PLACEHOLDER_URL, target.Url,
+		PLACEHOLDER_VIDEO_ID, target.VideoId,
+		PLACEHOLDER_POSITION, strconv.Itoa(int(target.Position.Seconds())),
+	)
+	for idx := range args {
+		args[idx] = replacer.Replace(args[idx])
+	}
+	return args, nil
+}
+
+// Open runs the opener for target and waits for it to exit. The error
+// contains the output of a failed opener.
+func Open(opener string, target Target) error {
+	args, err := Command(opener, target)
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		if last := lines[len(lines)-1]; last != "" {
+			return fmt.Errorf("%s: %w: %s", args[0], err, last)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
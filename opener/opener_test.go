@@ -0,0 +1,76 @@
+package opener_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/opener"
+)
+
+var target = opener.Target{
+	Url:      "https://youtube.com/watch?v=abc&list=PL1",
+	VideoId:  "abc",
+	Position: 90 * time.Second,
+}
+
+type CommandTest struct {
+	opener string
+	want   []string
+}
+
+func TestCommand(t *testing.T) {
+	tests := []CommandTest{
+		{"xdg-open", []string{"xdg-open", target.Url}},
+		{"mpv --start={position} {url}", []string{"mpv", "--start=90", target.Url}},
+		{"freetube https://youtu.be/{videoId}", []string{"freetube", "https://youtu.be/abc"}},
+		{`"my player" --title 'a b' {url}`, []string{"my player", "--title", "a b", target.Url}},
+	}
+
+	for _, test := range tests {
+		args, err := opener.Command(test.opener, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(args, "|") != strings.Join(test.want, "|") {
+			t.Fatalf("Wanted %q for %s, got %q", test.want, test.opener, args)
+		}
+	}
+
+	if _, err := opener.Command(`mpv "{url}`, target); err == nil {
+		t.Fatal("Wanted an error for an unterminated quote")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	playlist := data.Playlist{}
+	if opener.Resolve("xdg-open", &playlist) != "xdg-open" {
+		t.Fatal("Wanted the default opener without override")
+	}
+
+	playlist.Opener = "mpv"
+	if opener.Resolve("xdg-open", &playlist) != "mpv" {
+		t.Fatal("Wanted the opener of the playlist")
+	}
+}
+
+func TestOpenFailure(t *testing.T) {
+	err := opener.Open("sh -c 'echo no display >&2; exit 3'", target)
+	if err == nil || !strings.Contains(err.Error(), "no display") {
+		t.Fatalf("Wanted the output of the failed opener, got %v", err)
+	}
+}
+
+func TestForChapter(t *testing.T) {
+	video := data.Video{VideoId: "abc", PlaylistId: "PL1", Position: data.Duration{Duration: time.Minute}}
+	chapter := data.Chapter{Title: "Proof", Start: data.Duration{Duration: 125 * time.Second}}
+
+	target := opener.ForChapter(&video, chapter)
+	if target.Url != "https://youtube.com/watch?v=abc&list=PL1&t=125s" {
+		t.Fatalf("Wanted the url to start at the chapter, got %s", target.Url)
+	}
+	if target.Position != 125*time.Second {
+		t.Fatalf("Wanted the position of the chapter, got %v", target.Position)
+	}
+}
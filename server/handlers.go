@@ -0,0 +1,423 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/feed"
+)
+
+// playlistDetail is a playlist together with its videos
+type playlistDetail struct {
+	data.PlaylistSummary
+	Videos []data.VideoSummary `json:"videos"`
+}
+
+// refreshResult is the response of a refresh
+type refreshResult struct {
+	data.PlaylistSummary
+	NewVideos []data.VideoSummary `json:"new_videos"`
+}
+
+type addRequest struct {
+	Url string `json:"url"`
+}
+
+type updateVideoRequest struct {
+	Watched *bool `json:"watched"`
+}
+
+type setTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type setWatchedRequest struct {
+	Videos  []string `json:"videos"`
+	Watched bool     `json:"watched"`
+}
+
+func (s *Server) handleOpenApi(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi)
+}
+
+func videoSummaries(videos []data.Video) []data.VideoSummary {
+	summaries := []data.VideoSummary{}
+	for idx := range videos {
+		summaries = append(summaries, videos[idx].Summary())
+	}
+	return summaries
+}
+
+func (s *Server) handleListPlaylists(w http.ResponseWriter, r *http.Request) {
+	playlists, err := s.dr.GetPlaylists()
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	summaries := []data.PlaylistSummary{}
+	for idx := range playlists {
+		summaries = append(summaries, playlists[idx].Summary())
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) handleAddPlaylist(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApi(w) {
+		return
+	}
+
+	req := addRequest{}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	id, err := data.ParsePlaylistId(req.Url)
+	if err != nil || id == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid playlist url %q", req.Url))
+		return
+	}
+
+	playlist, err := s.yt.GetPlaylist(id)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	playlist.LastRefreshed = time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := data.GetPlaylist(s.dr, id); err == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("playlist %s is already tracked", id))
+		return
+	}
+
+	err = s.dr.SavePlaylist(&playlist)
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, playlistDetail{playlist.Summary(), videoSummaries(playlist.Videos)})
+}
+
+func (s *Server) handleGetPlaylist(w http.ResponseWriter, r *http.Request) {
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	playlist.Sort()
+	writeJSON(w, http.StatusOK, playlistDetail{playlist.Summary(), videoSummaries(playlist.Videos)})
+}
+
+func (s *Server) handleDeletePlaylist(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	err = s.dr.DeletePlaylist(playlist.Id)
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListVideos(w http.ResponseWriter, r *http.Request) {
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	// the order of the playlist unless another one is requested
+	if mode := r.URL.Query().Get("sort"); mode != "" {
+		err = data.CheckSortMode(mode)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		playlist.SortBy(mode, r.URL.Query().Get("order") == "desc")
+	} else {
+		playlist.Sort()
+	}
+
+	onlyUnwatched := r.URL.Query().Get("unwatched") == "true"
+	summaries := []data.VideoSummary{}
+	for idx := range playlist.Videos {
+		if onlyUnwatched && playlist.Videos[idx].Watched {
+			continue
+		}
+		summaries = append(summaries, playlist.Videos[idx].Summary())
+	}
+
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) handleUpdateVideo(w http.ResponseWriter, r *http.Request) {
+	req := updateVideoRequest{}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Watched == nil {
+		writeError(w, http.StatusBadRequest, errors.New("missing field watched"))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	video := playlist.FindVideo(r.PathValue("video"))
+	if video == nil {
+		writeDataError(w, fmt.Errorf("video %s is %w", r.PathValue("video"), data.ErrNotTracked))
+		return
+	}
+
+	err = s.dr.UpdateVideoWatched(playlist.Id, video.Id, *req.Watched)
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+	video.SetWatched(*req.Watched)
+
+	writeJSON(w, http.StatusOK, video.Summary())
+}
+
+// handleSetWatched sets the watched state of several videos at once
+func (s *Server) handleSetWatched(w http.ResponseWriter, r *http.Request) {
+	req := setWatchedRequest{}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	// nothing is changed if one of the videos is missing
+	videos := []*data.Video{}
+	missing := []string{}
+	for _, id := range req.Videos {
+		video := playlist.FindVideo(id)
+		if video == nil {
+			missing = append(missing, id)
+			continue
+		}
+		videos = append(videos, video)
+	}
+
+	if len(missing) > 0 {
+		writeDataError(w, fmt.Errorf("videos %s are %w", strings.Join(missing, ", "), data.ErrNotTracked))
+		return
+	}
+
+	changed := []data.VideoSummary{}
+	for _, video := range videos {
+		err = s.dr.UpdateVideoWatched(playlist.Id, video.Id, req.Watched)
+		if err != nil {
+			writeDataError(w, err)
+			return
+		}
+		video.SetWatched(req.Watched)
+		changed = append(changed, video.Summary())
+	}
+
+	writeJSON(w, http.StatusOK, changed)
+}
+
+// refresh refreshes the playlist with the given id and notifies the sinks
+// about the new videos. The vault is only locked to merge the videos, so
+// a slow fetch does not block other requests.
+func (s *Server) refresh(id string) (refreshResult, error) {
+	_, err := data.GetPlaylist(s.dr, id)
+	if err != nil {
+		return refreshResult{}, err
+	}
+
+	videos, err := s.yt.GetAllPlaylistVideos(id)
+	if err != nil {
+		return refreshResult{}, err
+	}
+
+	s.mutex.Lock()
+	playlist, newVideos, err := data.MergePlaylistVideos(s.dr, id, videos)
+	s.mutex.Unlock()
+	if err != nil {
+		return refreshResult{}, err
+	}
+
+	// the refresh itself succeeded, so a failed notification is no error
+	s.notifier.Notify(playlist, newVideos)
+
+	return refreshResult{playlist.Summary(), videoSummaries(newVideos)}, nil
+}
+
+func (s *Server) handleRefreshPlaylist(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApi(w) {
+		return
+	}
+
+	result, err := s.refresh(r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleRefreshAll(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApi(w) {
+		return
+	}
+
+	playlists, err := s.dr.GetPlaylists()
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	results := []refreshResult{}
+	for idx := range playlists {
+		result, err := s.refresh(playlists[idx].Id)
+		if err != nil {
+			writeDataError(w, fmt.Errorf("could not refresh %s: %w", playlists[idx].Title, err))
+			return
+		}
+		results = append(results, result)
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if !s.requireApi(w) {
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing query parameter q"))
+		return
+	}
+
+	playlists, err := s.yt.GetYoutubePlaylistsBySearch(query)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	summaries := []data.PlaylistSummary{}
+	for idx := range playlists {
+		summaries = append(summaries, playlists[idx].Summary())
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (s *Server) handleSetTags(w http.ResponseWriter, r *http.Request) {
+	req := setTagsRequest{}
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	playlist.Tags = []string{}
+	for _, tag := range req.Tags {
+		playlist.AddTag(tag)
+	}
+
+	err = s.dr.SavePlaylist(playlist)
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, playlist.Summary())
+}
+
+// feedOptions returns the options of a feed served at the url of r
+func feedOptions(r *http.Request) feed.Options {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	// the token is not part of the self link
+	self := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	return feed.Options{
+		Self:  self.String(),
+		Limit: limit,
+	}
+}
+
+func writeFeed(w http.ResponseWriter, atom *feed.Feed) {
+	w.Header().Set("Content-Type", feed.CONTENT_TYPE)
+	atom.Write(w)
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	playlists, err := s.dr.GetPlaylists()
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	writeFeed(w, feed.ForVault(playlists, feedOptions(r)))
+}
+
+func (s *Server) handlePlaylistFeed(w http.ResponseWriter, r *http.Request) {
+	playlist, err := data.GetPlaylist(s.dr, r.PathValue("id"))
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	writeFeed(w, feed.ForPlaylist(playlist, feedOptions(r)))
+}
+
+func (s *Server) handleTagFeed(w http.ResponseWriter, r *http.Request) {
+	playlists, err := s.dr.GetPlaylists()
+	if err != nil {
+		writeDataError(w, err)
+		return
+	}
+
+	writeFeed(w, feed.ForTag(playlists, r.PathValue("tag"), feedOptions(r)))
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/notify"
+)
+
+//go:embed openapi.json
+var openapi []byte
+
+// web is the single page interface served on /
+//
+//go:embed web
+var web embed.FS
+
+// Server exposes the vault as a json http api and serves the web interface
+type Server struct {
+	dr       data.DataRetriever
+	yt       *data.YouTubeApi
+	notifier *notify.Notifier
+	token    string
+
+	// mutex serializes changes to the vault, since the DataRetriever
+	// reads and writes whole playlists
+	mutex sync.Mutex
+	mux   *http.ServeMux
+}
+
+// New creates a server for the vault of dr. yt may be nil, the endpoints
+// that talk to youtube respond with 503 then. Every request to /api/
+// needs the token, it must not be empty.
+func New(dr data.DataRetriever, yt *data.YouTubeApi, notifier *notify.Notifier, token string) (*Server, error) {
+	if token == "" {
+		return nil, errors.New("the server needs a token")
+	}
+
+	s := &Server{
+		dr:       dr,
+		yt:       yt,
+		notifier: notifier,
+		token:    token,
+		mux:      http.NewServeMux(),
+	}
+	s.routes()
+	return s, nil
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/openapi.json", s.handleOpenApi)
+
+	s.mux.Handle("GET /api/playlists", s.auth(s.handleListPlaylists))
+	s.mux.Handle("POST /api/playlists", s.auth(s.handleAddPlaylist))
+	s.mux.Handle("GET /api/playlists/{id}", s.auth(s.handleGetPlaylist))
+	s.mux.Handle("DELETE /api/playlists/{id}", s.auth(s.handleDeletePlaylist))
+	s.mux.Handle("GET /api/playlists/{id}/videos", s.auth(s.handleListVideos))
+	s.mux.Handle("PATCH /api/playlists/{id}/videos/{video}", s.auth(s.handleUpdateVideo))
+	s.mux.Handle("POST /api/playlists/{id}/watched", s.auth(s.handleSetWatched))
+	s.mux.Handle("POST /api/playlists/{id}/refresh", s.auth(s.handleRefreshPlaylist))
+	s.mux.Handle("POST /api/refresh", s.auth(s.handleRefreshAll))
+	s.mux.Handle("GET /api/search", s.auth(s.handleSearch))
+	s.mux.Handle("PUT /api/playlists/{id}/tags", s.auth(s.handleSetTags))
+
+	// feed readers usually only support the token as query parameter
+	s.mux.Handle("GET /feed", s.auth(s.handleFeed))
+	s.mux.Handle("GET /feed/playlists/{id}", s.auth(s.handlePlaylistFeed))
+	s.mux.Handle("GET /feed/tags/{tag}", s.auth(s.handleTagFeed))
+
+	// the interface itself contains no data, it asks for the token
+	// before calling the api
+	webRoot, _ := fs.Sub(web, "web")
+	s.mux.Handle("GET /", http.FileServerFS(webRoot))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// auth only calls next if the request carries the token, either as
+// "Authorization: Bearer <token>" or as the query parameter "token"
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if header := r.Header.Get("Authorization"); header != "" {
+			token = strings.TrimPrefix(header, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tubevault"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// writeJSON writes v with the given status code
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+// apiError is the body of every error response
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{err.Error()})
+}
+
+// writeDataError writes err with 404 for untracked playlists and videos
+// and 500 for everything else
+func writeDataError(w http.ResponseWriter, err error) {
+	if errors.Is(err, data.ErrNotTracked) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// readJSON decodes the request body into v and writes an error response
+// if that fails
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+// requireApi writes 503 if the server has no youtube api
+func (s *Server) requireApi(w http.ResponseWriter) bool {
+	if s.yt == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("no youtube api key configured"))
+		return false
+	}
+	return true
+}
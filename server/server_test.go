@@ -0,0 +1,232 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+	"github.com/baumple/watchvault/feed"
+	"github.com/baumple/watchvault/server"
+)
+
+const TOKEN = "secret"
+
+func newTestServer(t *testing.T) (*httptest.Server, data.DataRetriever) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dr.SavePlaylist(&data.Playlist{
+		Id:    "PL1",
+		Title: "Lectures",
+		Videos: []data.Video{
+			{Id: "i2", VideoId: "v2", Title: "Two", PlaylistId: "PL1", PublishedAt: time.Unix(200, 0)},
+			{Id: "i1", VideoId: "v1", Title: "One", PlaylistId: "PL1", PublishedAt: time.Unix(100, 0)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := server.New(dr, nil, nil, TOKEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, dr
+}
+
+func request(t *testing.T, method string, url string, body string, v any) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+TOKEN)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		err = json.NewDecoder(resp.Body).Decode(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestAuth(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/api/playlists")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Wanted 401 without token, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/playlists?token=" + TOKEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Wanted 200 with the token as query parameter, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("Wanted the web interface without token, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Wanted the api description without token, got %d", resp.StatusCode)
+	}
+}
+
+func TestEmptyToken(t *testing.T) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an empty token would let every request without one through
+	_, err = server.New(dr, nil, nil, "")
+	if err == nil {
+		t.Fatal("Wanted an error for an empty token")
+	}
+}
+
+func TestPlaylists(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	summaries := []data.PlaylistSummary{}
+	status := request(t, http.MethodGet, ts.URL+"/api/playlists", "", &summaries)
+	if status != http.StatusOK || len(summaries) != 1 || summaries[0].Total != 2 {
+		t.Fatalf("Wanted one playlist with two videos, got %d %+v", status, summaries)
+	}
+
+	videos := []data.VideoSummary{}
+	request(t, http.MethodGet, ts.URL+"/api/playlists/PL1/videos?sort=published", "", &videos)
+	if len(videos) != 2 || videos[0].Id != "i1" {
+		t.Fatalf("Wanted the videos ordered by publish date, got %+v", videos)
+	}
+
+	request(t, http.MethodGet, ts.URL+"/api/playlists/PL1/videos?sort=published&order=desc", "", &videos)
+	if len(videos) != 2 || videos[0].Id != "i2" {
+		t.Fatalf("Wanted the newest video first, got %+v", videos)
+	}
+
+	status = request(t, http.MethodGet, ts.URL+"/api/playlists/PL1/videos?sort=random", "", nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("Wanted 400 for an unknown sort mode, got %d", status)
+	}
+
+	status = request(t, http.MethodGet, ts.URL+"/api/playlists/missing", "", nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("Wanted 404 for an untracked playlist, got %d", status)
+	}
+
+	status = request(t, http.MethodGet, ts.URL+"/api/search?q=go", "", nil)
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("Wanted 503 for search without youtube api, got %d", status)
+	}
+}
+
+func TestRefreshUntracked(t *testing.T) {
+	dr, err := data.NewJsonRetriever(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	yt, err := data.NewYouTubeApi("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := server.New(dr, &yt, nil, TOKEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	// the playlist is looked up before youtube is asked
+	status := request(t, http.MethodPost, ts.URL+"/api/playlists/missing/refresh", "", nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("Wanted 404 for refreshing an untracked playlist, got %d", status)
+	}
+}
+
+func TestWatched(t *testing.T) {
+	ts, dr := newTestServer(t)
+
+	video := data.VideoSummary{}
+	status := request(t, http.MethodPatch, ts.URL+"/api/playlists/PL1/videos/v1", `{"watched": true}`, &video)
+	if status != http.StatusOK || !video.Watched {
+		t.Fatalf("Wanted the video to be watched, got %d %+v", status, video)
+	}
+
+	status = request(t, http.MethodPost, ts.URL+"/api/playlists/PL1/watched", `{"videos": ["i1", "i2"], "watched": false}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Wanted 200 for the bulk update, got %d", status)
+	}
+
+	playlist, err := data.GetPlaylist(dr, "PL1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if playlist.WatchedCount() != 0 {
+		t.Fatalf("Wanted no watched videos in the vault, got %d", playlist.WatchedCount())
+	}
+
+	status = request(t, http.MethodPatch, ts.URL+"/api/playlists/PL1/videos/v1", `{}`, nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("Wanted 400 without watched field, got %d", status)
+	}
+
+	status = request(t, http.MethodDelete, ts.URL+"/api/playlists/PL1", "", nil)
+	if status != http.StatusNoContent {
+		t.Fatalf("Wanted 204 for the deletion, got %d", status)
+	}
+	if _, err := data.GetPlaylist(dr, "PL1"); err == nil {
+		t.Fatal("Wanted the playlist to be deleted")
+	}
+}
+
+func TestFeed(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/feed/playlists/PL1?token=" + TOKEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != feed.CONTENT_TYPE {
+		t.Fatalf("Wanted the atom feed with the token as query parameter, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	tags := data.PlaylistSummary{}
+	status := request(t, http.MethodPut, ts.URL+"/api/playlists/PL1/tags", `{"tags": ["uni", "uni"]}`, &tags)
+	if status != http.StatusOK || len(tags.Tags) != 1 {
+		t.Fatalf("Wanted a single tag, got %d %+v", status, tags)
+	}
+}
@@ -0,0 +1,332 @@
+"use strict";
+
+// token handling: a ?token= parameter is stored and removed from the url
+const params = new URLSearchParams(location.search);
+if (params.has("token")) {
+	localStorage.setItem("tubevault-token", params.get("token"));
+	history.replaceState(null, "", location.pathname + location.hash);
+}
+
+const app = document.getElementById("app");
+const statusBar = document.getElementById("status");
+const login = document.getElementById("login");
+
+// el creates an element with the given properties and children
+function el(tag, props = {}, ...children) {
+	const element = document.createElement(tag);
+	for (const [key, value] of Object.entries(props)) {
+		if (key.startsWith("on")) {
+			element.addEventListener(key.slice(2), value);
+		} else if (key === "class") {
+			element.className = value;
+		} else {
+			element[key] = value;
+		}
+	}
+	for (const child of children) {
+		if (child !== null && child !== undefined) {
+			element.append(child);
+		}
+	}
+	return element;
+}
+
+function showStatus(text, kind = "error") {
+	statusBar.textContent = text;
+	statusBar.className = kind;
+	statusBar.hidden = false;
+	clearTimeout(showStatus.timeout);
+	showStatus.timeout = setTimeout(() => (statusBar.hidden = true), 5000);
+}
+
+function askToken() {
+	return new Promise((resolve) => {
+		login.showModal();
+		login.addEventListener("close", () => {
+			localStorage.setItem("tubevault-token", document.getElementById("token").value);
+			resolve();
+		}, { once: true });
+	});
+}
+
+// api calls the json api and asks for the token if it is missing
+async function api(method, path, body) {
+	for (;;) {
+		const resp = await fetch("api" + path, {
+			method,
+			headers: {
+				"Authorization": "Bearer " + (localStorage.getItem("tubevault-token") || ""),
+				"Content-Type": "application/json",
+			},
+			body: body === undefined ? undefined : JSON.stringify(body),
+		});
+
+		if (resp.status === 401) {
+			await askToken();
+			continue;
+		}
+		if (resp.status === 204) {
+			return null;
+		}
+
+		const result = await resp.json();
+		if (!resp.ok) {
+			throw new Error(result.error || resp.statusText);
+		}
+		return result;
+	}
+}
+
+function formatDate(date) {
+	return new Date(date).toLocaleDateString();
+}
+
+async function showPlaylists() {
+	const playlists = await api("GET", "/playlists");
+
+	const list = el("ul", { class: "list" });
+	for (const playlist of playlists) {
+		list.append(el("li", {},
+			el("a", { class: "title", href: "#/playlist/" + encodeURIComponent(playlist.id) }, playlist.title),
+			playlist.updated ? el("span", { class: "new" }, "NEW") : null,
+			el("progress", { max: Math.max(playlist.total, 1), value: playlist.watched }),
+			el("span", { class: "meta" }, `${playlist.watched}/${playlist.total}`),
+			el("button", {
+				title: "Stop tracking this playlist",
+				onclick: async () => {
+					if (!confirm(`Remove ${playlist.title}?`)) {
+						return;
+					}
+					await api("DELETE", "/playlists/" + encodeURIComponent(playlist.id));
+					route();
+				},
+			}, "Remove"),
+		));
+	}
+
+	app.replaceChildren(
+		el("h2", {}, "Tracked playlists"),
+		playlists.length ? list : el("p", {}, "No playlists yet, add one with the search."),
+	);
+}
+
+// showPlaylist renders the videos of a playlist. Like the visual mode of
+// the terminal interface a range of videos can be selected, with shift-click
+// or with v and j/k, and toggled at once.
+async function showPlaylist(id) {
+	const playlist = await api("GET", "/playlists/" + encodeURIComponent(id));
+	const videos = playlist.videos;
+
+	let cursor = 0;
+	let anchor = null;
+	const rows = [];
+
+	const selection = () => {
+		if (anchor === null) {
+			return [cursor, cursor];
+		}
+		return [Math.min(anchor, cursor), Math.max(anchor, cursor)];
+	};
+
+	const render = () => {
+		const [start, end] = selection();
+		rows.forEach((row, idx) => {
+			row.classList.toggle("selected", idx >= start && idx <= end);
+			row.classList.toggle("watched", videos[idx].watched);
+			row.querySelector("input").checked = videos[idx].watched;
+		});
+		const watched = videos.filter((video) => video.watched).length;
+		progress.value = watched;
+		counter.textContent = `${watched}/${videos.length}`;
+	};
+
+	const setWatched = async (indices, watched) => {
+		const ids = indices.map((idx) => videos[idx].id);
+		await api("POST", `/playlists/${encodeURIComponent(id)}/watched`, { videos: ids, watched });
+		indices.forEach((idx) => (videos[idx].watched = watched));
+		render();
+	};
+
+	const toggleSelection = () => {
+		const [start, end] = selection();
+		const indices = [];
+		for (let idx = start; idx <= end; idx++) {
+			indices.push(idx);
+		}
+		// like the terminal interface every video is flipped on its own
+		const unwatched = indices.filter((idx) => !videos[idx].watched);
+		const watched = indices.filter((idx) => videos[idx].watched);
+		anchor = null;
+		return Promise.all([
+			unwatched.length > 0 ? setWatched(unwatched, true) : null,
+			watched.length > 0 ? setWatched(watched, false) : null,
+		]).catch((err) => showStatus(err.message));
+	};
+
+	const list = el("ul", { class: "list" });
+	videos.forEach((video, idx) => {
+		const row = el("li", {
+			onclick: (event) => {
+				if (event.target.tagName === "INPUT" || event.target.tagName === "A") {
+					return;
+				}
+				if (event.shiftKey) {
+					anchor = anchor === null ? cursor : anchor;
+				} else {
+					anchor = null;
+				}
+				cursor = idx;
+				render();
+			},
+		},
+			el("input", {
+				type: "checkbox",
+				checked: video.watched,
+				onchange: (event) => setWatched([idx], event.target.checked).catch((err) => showStatus(err.message)),
+			}),
+			el("span", { class: "title" }, video.title),
+			el("span", { class: "meta" }, formatDate(video.published_at)),
+			el("a", { href: video.url, target: "_blank", rel: "noopener" }, "Open"),
+		);
+		rows.push(row);
+		list.append(row);
+	});
+
+	const progress = el("progress", { max: Math.max(videos.length, 1) });
+	const counter = el("span", { class: "meta" });
+
+	app.replaceChildren(
+		el("h2", {}, playlist.title),
+		el("p", { class: "meta" }, playlist.description),
+		el("div", { class: "toolbar" },
+			progress,
+			counter,
+			el("span", { class: "grow" }),
+			el("button", { onclick: toggleSelection, title: "Toggle the selected videos (space)" }, "Toggle selected"),
+			el("button", {
+				onclick: async () => {
+					const result = await api("POST", `/playlists/${encodeURIComponent(id)}/refresh`);
+					showStatus(`${result.new_videos.length} new videos`, "info");
+					route();
+				},
+			}, "Refresh"),
+			el("a", { href: playlist.url, target: "_blank", rel: "noopener" }, "YouTube"),
+		),
+		el("p", { class: "meta" }, "Shift-click or v + j/k selects a range, space toggles the selection."),
+		list,
+	);
+	render();
+
+	keyHandler = (event) => {
+		switch (event.key) {
+		case "j":
+		case "ArrowDown":
+			cursor = Math.min(cursor + 1, videos.length - 1);
+			break;
+		case "k":
+		case "ArrowUp":
+			cursor = Math.max(cursor - 1, 0);
+			break;
+		case "g":
+			cursor = 0;
+			break;
+		case "G":
+			cursor = videos.length - 1;
+			break;
+		case "v":
+			anchor = anchor === null ? cursor : null;
+			break;
+		case "Escape":
+			anchor = null;
+			break;
+		case " ":
+			event.preventDefault();
+			toggleSelection();
+			return;
+		default:
+			return;
+		}
+		event.preventDefault();
+		render();
+		rows[cursor]?.scrollIntoView({ block: "nearest" });
+	};
+}
+
+function showSearch() {
+	const results = el("ul", { class: "list" });
+	const input = el("input", { type: "search", placeholder: "Search playlists", class: "grow", required: true });
+
+	const form = el("form", {
+		class: "toolbar",
+		onsubmit: async (event) => {
+			event.preventDefault();
+			const playlists = await api("GET", "/search?q=" + encodeURIComponent(input.value));
+			results.replaceChildren(...playlists.map((playlist) => el("li", {},
+				el("span", { class: "title" }, playlist.title),
+				el("span", { class: "meta" }, playlist.description.slice(0, 80)),
+				el("button", {
+					onclick: async (event) => {
+						event.target.disabled = true;
+						await api("POST", "/playlists", { url: playlist.id });
+						showStatus(`Added ${playlist.title}`, "info");
+					},
+				}, "Add"),
+			)));
+		},
+	}, input, el("button", {}, "Search"));
+
+	app.replaceChildren(
+		el("h2", {}, "Search"),
+		form,
+		el("p", { class: "meta" }, "A playlist url can be added directly:"),
+		el("form", {
+			class: "toolbar",
+			onsubmit: async (event) => {
+				event.preventDefault();
+				const url = event.target.elements.url.value;
+				const playlist = await api("POST", "/playlists", { url });
+				location.hash = "#/playlist/" + encodeURIComponent(playlist.id);
+			},
+		}, el("input", { name: "url", placeholder: "https://youtube.com/playlist?list=...", class: "grow", required: true }), el("button", {}, "Add")),
+		results,
+	);
+	input.focus();
+}
+
+let keyHandler = null;
+document.addEventListener("keydown", (event) => {
+	if (keyHandler && !["INPUT", "TEXTAREA"].includes(event.target.tagName)) {
+		keyHandler(event);
+	}
+});
+
+document.getElementById("refresh-all").addEventListener("click", async () => {
+	try {
+		const results = await api("POST", "/refresh");
+		const count = results.reduce((sum, result) => sum + result.new_videos.length, 0);
+		showStatus(`${count} new videos`, "info");
+		route();
+	} catch (err) {
+		showStatus(err.message);
+	}
+});
+
+async function route() {
+	keyHandler = null;
+	const hash = location.hash.replace(/^#/, "");
+	try {
+		if (hash.startsWith("/playlist/")) {
+			await showPlaylist(decodeURIComponent(hash.slice("/playlist/".length)));
+		} else if (hash === "/search") {
+			showSearch();
+		} else {
+			await showPlaylists();
+		}
+	} catch (err) {
+		showStatus(err.message);
+	}
+}
+
+window.addEventListener("hashchange", route);
+window.addEventListener("unhandledrejection", (event) => showStatus(event.reason.message));
+route();
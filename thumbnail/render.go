@@ -0,0 +1,220 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// protocols to show images in a terminal
+const (
+	PROTOCOL_AUTO      = "auto"
+	PROTOCOL_KITTY     = "kitty"
+	PROTOCOL_SIXEL     = "sixel"
+	PROTOCOL_HALFBLOCK = "halfblock"
+	PROTOCOL_NONE      = "none"
+)
+
+// the assumed size of a terminal cell in pixels
+const (
+	CELL_WIDTH  = 10
+	CELL_HEIGHT = 20
+)
+
+// KITTY_CHUNK is the maximum payload of a kitty graphics escape
+const KITTY_CHUNK = 4096
+
+// CheckProtocol returns an error for unknown protocols
+func CheckProtocol(protocol string) error {
+	switch protocol {
+	case PROTOCOL_AUTO, PROTOCOL_KITTY, PROTOCOL_SIXEL, PROTOCOL_HALFBLOCK, PROTOCOL_NONE:
+		return nil
+	}
+	return fmt.Errorf("unknown thumbnail protocol %q, supported: %s, %s, %s, %s, %s", protocol,
+		PROTOCOL_AUTO, PROTOCOL_KITTY, PROTOCOL_SIXEL, PROTOCOL_HALFBLOCK, PROTOCOL_NONE)
+}
+
+// Detect guesses the best protocol the terminal supports from its
+// environment variables
+func Detect(getenv func(string) string) string {
+	term := getenv("TERM")
+	program := getenv("TERM_PROGRAM")
+
+	switch {
+	case term == "" || term == "dumb":
+		return PROTOCOL_NONE
+	// graphics would have to be passed through the multiplexer
+	case getenv("TMUX") != "" || strings.HasPrefix(term, "screen") || strings.HasPrefix(term, "tmux"):
+		return PROTOCOL_HALFBLOCK
+	case term == "xterm-kitty" || getenv("KITTY_WINDOW_ID") != "" ||
+		term == "xterm-ghostty" || program == "ghostty" || program == "WezTerm":
+		return PROTOCOL_KITTY
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") ||
+		strings.Contains(term, "sixel") || program == "contour" || program == "mlterm" ||
+		getenv("WT_SESSION") != "":
+		return PROTOCOL_SIXEL
+	}
+	return PROTOCOL_HALFBLOCK
+}
+
+// Rows returns the number of terminal rows an image of the given width in
+// cells takes up
+func Rows(img image.Image, cols int) int {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 {
+		return 0
+	}
+	return max(1, cols*CELL_WIDTH*bounds.Dy()/bounds.Dx()/CELL_HEIGHT)
+}
+
+// resize scales img to width x height pixels, averaging the pixels that
+// fall into a target pixel
+func resize(img image.Image, width int, height int) *image.RGBA {
+	bounds := img.Bounds()
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, _ := img.At(sx, sy).RGBA()
+					r, g, b, n = r+pr, g+pg, b+pb, n+1
+				}
+			}
+			resized.Set(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), 0xff})
+		}
+	}
+	return resized
+}
+
+// HalfBlock renders img with "▀" characters, the foreground color is the
+// upper and the background color the lower pixel. It returns one string
+// per row.
+func HalfBlock(img image.Image, cols int, rows int) []string {
+	resized := resize(img, cols, rows*2)
+
+	lines := []string{}
+	for y := 0; y < rows; y++ {
+		line := strings.Builder{}
+		for x := 0; x < cols; x++ {
+			upper := resized.RGBAAt(x, y*2)
+			lower := resized.RGBAAt(x, y*2+1)
+			fmt.Fprintf(&line, "\033[38;2;%d;%d;%dm\033[48;2;%d;%d;%dm▀",
+				upper.R, upper.G, upper.B, lower.R, lower.G, lower.B)
+		}
+		line.WriteString("\033[0m")
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// Kitty returns the escape sequences that transmit img with the given id
+// and show it over cols x rows cells at the cursor without moving it
+func Kitty(img image.Image, id int, cols int, rows int) (string, error) {
+	encoded := bytes.Buffer{}
+	err := png.Encode(&encoded, resize(img, cols*CELL_WIDTH, rows*CELL_HEIGHT))
+	if err != nil {
+		return "", err
+	}
+	payload := base64.StdEncoding.EncodeToString(encoded.Bytes())
+
+	out := strings.Builder{}
+	for start := 0; start < len(payload); start += KITTY_CHUNK {
+		end := min(start+KITTY_CHUNK, len(payload))
+		more := 1
+		if end == len(payload) {
+			more = 0
+		}
+
+		if start == 0 {
+			fmt.Fprintf(&out, "\033_Ga=T,f=100,i=%d,p=1,c=%d,r=%d,C=1,q=2,m=%d;%s\033\\",
+				id, cols, rows, more, payload[start:end])
+		} else {
+			fmt.Fprintf(&out, "\033_Gm=%d;%s\033\\", more, payload[start:end])
+		}
+	}
+	return out.String(), nil
+}
+
+// KittyDelete returns the escape sequence that removes every image shown
+// with Kitty
+func KittyDelete() string {
+	return "\033_Ga=d,d=A,q=2\033\\"
+}
+
+// Sixel returns img as sixel graphics of cols x rows cells. The colors are
+// reduced to a 6x6x6 color cube.
+func Sixel(img image.Image, cols int, rows int) string {
+	width := cols * CELL_WIDTH
+	height := rows * CELL_HEIGHT
+	resized := resize(img, width, height)
+
+	index := func(c color.RGBA) int {
+		return int(c.R)*6/256*36 + int(c.G)*6/256*6 + int(c.B)*6/256
+	}
+
+	out := strings.Builder{}
+	// "q" starts sixel data, the raster attributes set the size
+	fmt.Fprintf(&out, "\033Pq\"1;1;%d;%d", width, height)
+	for i := 0; i < 216; i++ {
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", i, i/36*100/5, i/6%6*100/5, i%6*100/5)
+	}
+
+	for band := 0; band < height; band += 6 {
+		// the sixels of every color used in this band
+		sixels := map[int][]byte{}
+		order := []int{}
+		for x := 0; x < width; x++ {
+			for bit := 0; bit < 6 && band+bit < height; bit++ {
+				c := index(resized.RGBAAt(x, band+bit))
+				if _, ok := sixels[c]; !ok {
+					sixels[c] = make([]byte, width)
+					order = append(order, c)
+				}
+				sixels[c][x] |= 1 << bit
+			}
+		}
+
+		for idx, c := range order {
+			if idx > 0 {
+				out.WriteByte('$')
+			}
+			fmt.Fprintf(&out, "#%d", c)
+			writeSixelRow(&out, sixels[c])
+		}
+		out.WriteByte('-')
+	}
+
+	out.WriteString("\033\\")
+	return out.String()
+}
+
+// writeSixelRow writes a row of sixels with run length encoding
+func writeSixelRow(out *strings.Builder, row []byte) {
+	for x := 0; x < len(row); {
+		run := 1
+		for x+run < len(row) && row[x+run] == row[x] {
+			run++
+		}
+
+		char := byte(63 + row[x])
+		if run > 3 {
+			fmt.Fprintf(out, "!%d%c", run, char)
+		} else {
+			for i := 0; i < run; i++ {
+				out.WriteByte(char)
+			}
+		}
+		x += run
+	}
+}
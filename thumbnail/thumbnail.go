@@ -0,0 +1,124 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/baumple/watchvault/data"
+)
+
+const (
+	// CACHE_DIR is the directory of the thumbnails in the cache dir
+	CACHE_DIR = "thumbnails"
+	// DEFAULT_BASE_URL serves the thumbnails of youtube videos
+	DEFAULT_BASE_URL = "https://i.ytimg.com/vi"
+	HTTP_TIMEOUT     = 10 * time.Second
+)
+
+// Cache fetches the thumbnail of a video once and keeps it on disk
+type Cache struct {
+	dir     string
+	baseUrl string
+	client  *http.Client
+}
+
+// NewCache creates a cache in dir, usually the cache dir of tubevault
+func NewCache(dir string) *Cache {
+	return &Cache{
+		dir:     filepath.Join(dir, CACHE_DIR),
+		baseUrl: DEFAULT_BASE_URL,
+		client:  &http.Client{Timeout: HTTP_TIMEOUT},
+	}
+}
+
+// WithBaseUrl fetches the thumbnails from another server, e.g. in tests
+func (c *Cache) WithBaseUrl(baseUrl string) *Cache {
+	c.baseUrl = baseUrl
+	return c
+}
+
+// Get returns the thumbnail of the video with the given youtube id
+func (c *Cache) Get(videoId string) (image.Image, error) {
+	if videoId == "" {
+		return nil, fmt.Errorf("no video id")
+	}
+
+	path := filepath.Join(c.dir, filepath.Base(videoId)+".jpg")
+	content, err := os.ReadFile(path)
+	if err == nil {
+		img, decodeErr := jpeg.Decode(bytes.NewReader(content))
+		if decodeErr == nil {
+			return img, nil
+		}
+
+		// a broken thumbnail in the cache is fetched again
+		err = os.Remove(path)
+		if err == nil {
+			err = fs.ErrNotExist
+		}
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	content, err = c.fetch(videoId)
+	if err != nil {
+		return nil, err
+	}
+	// only thumbnails that can be decoded are stored
+	img, err := jpeg.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	err = c.store(path, content)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func (c *Cache) fetch(videoId string) ([]byte, error) {
+	resp, err := c.client.Get(c.baseUrl + "/" + videoId + "/mqdefault.jpg")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch the thumbnail of %s: %s", videoId, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// store writes the thumbnail atomically, so a second instance never
+// reads half a file
+func (c *Cache) store(path string, content []byte) error {
+	err := os.MkdirAll(c.dir, data.DIR_PERMISSIONS)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(c.dir, ".thumbnail-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,174 @@
+package thumbnail_test
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baumple/watchvault/thumbnail"
+)
+
+// testImage is a 16:9 image with a red upper and a blue lower half
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 180))
+	for y := 0; y < 180; y++ {
+		for x := 0; x < 320; x++ {
+			c := color.RGBA{0xff, 0, 0, 0xff}
+			if y >= 90 {
+				c = color.RGBA{0, 0, 0xff, 0xff}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+type DetectTest struct {
+	env  map[string]string
+	want string
+}
+
+func TestDetect(t *testing.T) {
+	tests := []DetectTest{
+		{map[string]string{}, thumbnail.PROTOCOL_NONE},
+		{map[string]string{"TERM": "dumb"}, thumbnail.PROTOCOL_NONE},
+		{map[string]string{"TERM": "xterm-kitty"}, thumbnail.PROTOCOL_KITTY},
+		{map[string]string{"TERM": "xterm-256color", "TERM_PROGRAM": "WezTerm"}, thumbnail.PROTOCOL_KITTY},
+		{map[string]string{"TERM": "foot"}, thumbnail.PROTOCOL_SIXEL},
+		{map[string]string{"TERM": "xterm-256color", "WT_SESSION": "1"}, thumbnail.PROTOCOL_SIXEL},
+		{map[string]string{"TERM": "xterm-kitty", "TMUX": "/tmp/tmux"}, thumbnail.PROTOCOL_HALFBLOCK},
+		{map[string]string{"TERM": "xterm-256color"}, thumbnail.PROTOCOL_HALFBLOCK},
+	}
+
+	for _, test := range tests {
+		got := thumbnail.Detect(func(key string) string { return test.env[key] })
+		if got != test.want {
+			t.Fatalf("Wanted %s for %v, got %s", test.want, test.env, got)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/abc/mqdefault.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		jpeg.Encode(w, testImage(), nil)
+	}))
+	defer server.Close()
+
+	cache := thumbnail.NewCache(t.TempDir()).WithBaseUrl(server.URL)
+	for i := 0; i < 2; i++ {
+		img, err := cache.Get("abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != 320 {
+			t.Fatalf("Wanted a width of 320, got %d", img.Bounds().Dx())
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Wanted the thumbnail to be fetched once, got %d requests", requests)
+	}
+
+	if _, err := cache.Get("missing"); err == nil {
+		t.Fatal("Wanted an error for a missing thumbnail")
+	}
+}
+
+func TestCacheBrokenFile(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		jpeg.Encode(w, testImage(), nil)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, thumbnail.CACHE_DIR, "abc.jpg")
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte("not a jpeg"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := thumbnail.NewCache(dir).WithBaseUrl(server.URL)
+	for i := 0; i < 2; i++ {
+		_, err := cache.Get("abc")
+		if err != nil {
+			t.Fatalf("Wanted the broken thumbnail to be fetched again, got %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("Wanted the thumbnail to be fetched once, got %d requests", requests)
+	}
+}
+
+func TestHalfBlock(t *testing.T) {
+	img := testImage()
+	rows := thumbnail.Rows(img, 32)
+	if rows != 9 {
+		t.Fatalf("Wanted 9 rows for 32 columns, got %d", rows)
+	}
+
+	lines := thumbnail.HalfBlock(img, 32, rows)
+	if len(lines) != rows {
+		t.Fatalf("Wanted %d lines, got %d", rows, len(lines))
+	}
+	if strings.Count(lines[0], "▀") != 32 {
+		t.Fatalf("Wanted 32 blocks per line, got %q", lines[0])
+	}
+	if !strings.Contains(lines[0], "\033[38;2;255;0;0m\033[48;2;255;0;0m") {
+		t.Fatalf("Wanted the first line to be red, got %q", lines[0])
+	}
+	if !strings.Contains(lines[rows-1], "\033[38;2;0;0;255m\033[48;2;0;0;255m") {
+		t.Fatalf("Wanted the last line to be blue, got %q", lines[rows-1])
+	}
+}
+
+func TestKitty(t *testing.T) {
+	out, err := thumbnail.Kitty(testImage(), 7, 32, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(out, "\033_Ga=T,f=100,i=7,p=1,c=32,r=9,") {
+		t.Fatalf("Wanted the image to be placed over 32x9 cells, got %q", out[:40])
+	}
+	if !strings.HasSuffix(out, "\033\\") || strings.Count(out, "m=0;") != 1 {
+		t.Fatal("Wanted exactly one final chunk")
+	}
+	for _, chunk := range strings.Split(out, "\033\\") {
+		if _, payload, ok := strings.Cut(chunk, ";"); ok && len(payload) > thumbnail.KITTY_CHUNK {
+			t.Fatalf("Wanted chunks of at most %d bytes, got %d", thumbnail.KITTY_CHUNK, len(payload))
+		}
+	}
+}
+
+func TestSixel(t *testing.T) {
+	out := thumbnail.Sixel(testImage(), 4, 2)
+
+	if !strings.HasPrefix(out, "\033Pq\"1;1;40;40") || !strings.HasSuffix(out, "\033\\") {
+		t.Fatalf("Wanted a 40x40 sixel image, got %q", out[:20])
+	}
+	// 40 pixel rows are 7 bands of 6 pixels
+	if bands := strings.Count(out, "-"); bands != 7 {
+		t.Fatalf("Wanted 7 bands, got %d", bands)
+	}
+	// red (palette 180) fills the first band completely
+	if !strings.Contains(out, "#180!40~") {
+		t.Fatal("Wanted a run of 40 red sixels")
+	}
+}
@@ -0,0 +1,31 @@
+package utility
+
+import (
+	"unicode"
+)
+
+// FuzzyMatch reports whether the runes of pattern appear in text in the
+// same order, ignoring case. It returns the indices of the matched runes
+// of text or nil if text does not match. Spaces in pattern are ignored.
+func FuzzyMatch(pattern string, text string) []int {
+	indices := []int{}
+	textRunes := []rune(text)
+
+	next := 0
+	for _, p := range pattern {
+		if unicode.IsSpace(p) {
+			continue
+		}
+		p = unicode.ToLower(p)
+
+		for next < len(textRunes) && unicode.ToLower(textRunes[next]) != p {
+			next++
+		}
+		if next == len(textRunes) {
+			return nil
+		}
+		indices = append(indices, next)
+		next++
+	}
+	return indices
+}
@@ -1,14 +1,18 @@
 package utility
 
+// SplitEveryN splits s into parts of at most interval cells. Characters
+// are never split, one wider than interval gets a part of its own.
 func SplitEveryN(s string, interval int) []string {
-        substrings := []string{}
-        for i := 0; i < len(s); i++ {
-                if i % interval == 0 {
-                        substrings = append(substrings, "")
-                }
+	substrings := []string{}
+	width := 0
+	segments(s, func(segment string, w int) {
+		if len(substrings) == 0 || (width+w > interval && width > 0) {
+			substrings = append(substrings, "")
+			width = 0
+		}
 
-                substrings[len(substrings) - 1] += string(s[i])
-        }
-        return substrings
+		substrings[len(substrings)-1] += segment
+		width += w
+	})
+	return substrings
 }
-
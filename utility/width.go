@@ -0,0 +1,130 @@
+package utility
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// ELLIPSIS marks text that was cut off
+const ELLIPSIS = "…"
+
+const ESC = '\033'
+
+// escapeLen returns the length in bytes of the escape sequence s starts
+// with, 0 if it does not start with one
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != ESC {
+		return 0
+	}
+
+	switch s[1] {
+	case '[': // CSI, ends with a byte in @-~
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']', 'P', '_', '^', 'X': // OSC and friends, end with BEL or ST
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == ESC && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
+
+// isEscape reports whether segment is an escape sequence
+func isEscape(segment string) bool {
+	return len(segment) > 1 && segment[0] == ESC
+}
+
+// segments calls fn for every escape sequence and every grapheme cluster
+// in s with the number of cells it takes up
+func segments(s string, fn func(segment string, width int)) {
+	for len(s) > 0 {
+		if n := escapeLen(s); n > 0 {
+			fn(s[:n], 0)
+			s = s[n:]
+			continue
+		}
+
+		// graphemes do not span escape sequences
+		end := strings.IndexRune(s[1:], ESC) + 1
+		if end == 0 {
+			end = len(s)
+		}
+		text := s[:end]
+		state := -1
+		for len(text) > 0 {
+			var cluster string
+			cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+			fn(cluster, runewidth.StringWidth(cluster))
+		}
+		s = s[end:]
+	}
+}
+
+// Width returns the number of terminal cells s takes up. Escape sequences
+// take up none, wide characters two.
+func Width(s string) int {
+	width := 0
+	segments(s, func(_ string, w int) {
+		width += w
+	})
+	return width
+}
+
+// StripAnsi removes the escape sequences from s
+func StripAnsi(s string) string {
+	var b strings.Builder
+	segments(s, func(segment string, w int) {
+		if !isEscape(segment) {
+			b.WriteString(segment)
+		}
+	})
+	return b.String()
+}
+
+// Truncate cuts s down to at most width cells. If anything was cut off
+// the last cell is an ellipsis. Escape sequences are kept so styles are
+// still reset.
+func Truncate(s string, width int) string {
+	if Width(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	used := 0
+	cut := false
+	segments(s, func(segment string, w int) {
+		switch {
+		case isEscape(segment):
+			b.WriteString(segment)
+		case !cut && used+w <= width-1:
+			b.WriteString(segment)
+			used += w
+		case !cut:
+			b.WriteString(ELLIPSIS)
+			cut = true
+		}
+	})
+	return b.String()
+}
+
+// Pad fills s with spaces up to width cells. Longer strings are
+// truncated.
+func Pad(s string, width int) string {
+	s = Truncate(s, width)
+	return s + strings.Repeat(" ", max(width-Width(s), 0))
+}
@@ -0,0 +1,41 @@
+package utility
+
+import "strings"
+
+// WordWrap breaks text into lines of at most width cells. Lines are broken
+// at spaces, words longer than a line are split. Line breaks in text are
+// kept.
+func WordWrap(text string, width int) []string {
+	lines := []string{}
+	if width <= 0 {
+		return lines
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			// split words that do not fit on a line of their own
+			if Width(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+				}
+				parts := SplitEveryN(word, width)
+				lines = append(lines, parts[:len(parts)-1]...)
+				line = ""
+				word = parts[len(parts)-1]
+			}
+
+			switch {
+			case line == "":
+				line = word
+			case Width(line)+1+Width(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}